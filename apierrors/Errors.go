@@ -0,0 +1,95 @@
+package apierrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+//Kind Classifies an error by its cause, each kind corresponds to a gRPC status code
+type Kind int
+
+const (
+	//Internal Unclassified errors, e.g. failed database or object storage requests
+	Internal Kind = iota
+	//NotFound The requested resource does not exist
+	NotFound
+	//PermissionDenied The caller is not allowed to perform the requested action
+	PermissionDenied
+	//Unauthenticated The caller could not be identified
+	Unauthenticated
+	//AlreadyExists The resource to create already exists
+	AlreadyExists
+	//FailedPrecondition The resource is not in the state required for the requested action
+	FailedPrecondition
+	//InvalidArgument The request contains invalid values
+	InvalidArgument
+)
+
+var kindNames = map[Kind]string{
+	Internal:           "INTERNAL",
+	NotFound:           "NOT_FOUND",
+	PermissionDenied:   "PERMISSION_DENIED",
+	Unauthenticated:    "UNAUTHENTICATED",
+	AlreadyExists:      "ALREADY_EXISTS",
+	FailedPrecondition: "FAILED_PRECONDITION",
+	InvalidArgument:    "INVALID_ARGUMENT",
+}
+
+func (kind Kind) String() string {
+	return kindNames[kind]
+}
+
+//Error An error with a kind and optional information about the affected resource
+type Error struct {
+	Kind         Kind
+	Message      string
+	ResourceType string
+	ResourceID   string
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+//KindOf Returns the kind of an error, errors that are not of type *Error are Internal
+func KindOf(err error) Kind {
+	var apiError *Error
+	if errors.As(err, &apiError) {
+		return apiError.Kind
+	}
+
+	return Internal
+}
+
+//Is Checks whether the error is of the given kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+//New Creates a new error of the given kind
+func New(kind Kind, format string, args ...interface{}) error {
+	return &Error{
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+//NewNotFound Creates an error for a resource that does not exist
+func NewNotFound(resourceType string, resourceID string) error {
+	return &Error{
+		Kind:         NotFound,
+		Message:      fmt.Sprintf("Could not find %v with ID %v", resourceType, resourceID),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+	}
+}
+
+//NewPermissionDenied Creates an error for a request that lacks the required right on a resource
+func NewPermissionDenied(right fmt.Stringer, resourceType fmt.Stringer, resourceID string) error {
+	return &Error{
+		Kind:         PermissionDenied,
+		Message:      fmt.Sprintf("Access denied: Can not authorize %v access to %v %v", right, resourceType, resourceID),
+		ResourceType: resourceType.String(),
+		ResourceID:   resourceID,
+	}
+}
@@ -10,4 +10,5 @@ import (
 type AuthHandler interface {
 	Authorize(requestContext context.Context, resource models.Resource, requiredRight models.Right, resourceID string) (bool, error)
 	UserID(requestContext context.Context) (string, error)
+	TokenType(requestContext context.Context) (TokenType, error)
 }
@@ -0,0 +1,338 @@
+package authhandler
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
+)
+
+//defaultJWKSRefreshInterval Time after which the key set is fetched again if none is configured
+const defaultJWKSRefreshInterval = time.Hour
+
+//defaultJWKSMinRefreshInterval Minimal time between two fetches of the key set triggered by unknown key ids
+const defaultJWKSMinRefreshInterval = time.Minute
+
+//defaultJWTClockSkew Tolerated clock difference to the issuer when checking the validity period of a token
+const defaultJWTClockSkew = time.Minute
+
+//jwtAlgorithms The supported signature algorithms and the curve each ECDSA algorithm is bound to
+//Symmetric algorithms and "none" are rejected
+var jwtAlgorithms = map[jose.SignatureAlgorithm]elliptic.Curve{
+	jose.RS256: nil,
+	jose.RS384: nil,
+	jose.RS512: nil,
+	jose.ES256: elliptic.P256(),
+	jose.ES384: elliptic.P384(),
+	jose.ES512: elliptic.P521(),
+}
+
+//JWTValidator Validates JWT access tokens locally with the public keys of the issuer
+//The key set is discovered through the OpenID Connect discovery document of the issuer unless a JWKS URL is configured
+type JWTValidator struct {
+	Issuer             string
+	Audience           string
+	JWKSURL            string
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+	ClockSkew          time.Duration
+	HTTPClient         *http.Client
+	now                func() time.Time
+	mutex              sync.RWMutex
+	keys               map[string]jose.JSONWebKey
+	lastFetch          time.Time
+	fetchGroup         singleflight.Group
+}
+
+//NewJWTValidator Creates a new validator based on the Config.OAuth2Auth.JWT section of the config
+//Issuer and Audience are required, tokens that are issued for other services are always rejected
+func NewJWTValidator() (*JWTValidator, error) {
+	issuer := viper.GetString("Config.OAuth2Auth.JWT.Issuer")
+	if issuer == "" {
+		err := errors.New("Issuer has to be provided in config as 'Config.OAuth2Auth.JWT.Issuer'")
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	audience := viper.GetString("Config.OAuth2Auth.JWT.Audience")
+	if audience == "" {
+		err := errors.New("Audience has to be provided in config as 'Config.OAuth2Auth.JWT.Audience'")
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	refreshInterval := viper.GetDuration("Config.OAuth2Auth.JWT.JWKSRefreshInterval")
+	if refreshInterval == 0 {
+		refreshInterval = defaultJWKSRefreshInterval
+	}
+
+	clockSkew := defaultJWTClockSkew
+	if viper.IsSet("Config.OAuth2Auth.JWT.ClockSkew") {
+		clockSkew = viper.GetDuration("Config.OAuth2Auth.JWT.ClockSkew")
+	}
+
+	validator := JWTValidator{
+		Issuer:             issuer,
+		Audience:           audience,
+		JWKSURL:            viper.GetString("Config.OAuth2Auth.JWT.JWKSURL"),
+		RefreshInterval:    refreshInterval,
+		MinRefreshInterval: defaultJWKSMinRefreshInterval,
+		ClockSkew:          clockSkew,
+		HTTPClient:         &http.Client{Timeout: 10 * time.Second},
+		now:                time.Now,
+	}
+
+	return &validator, nil
+}
+
+//UserID Validates the token and returns its subject claim
+func (validator *JWTValidator) UserID(accessToken string) (string, error) {
+	token, err := jwt.ParseSigned(accessToken)
+	if err != nil {
+		return "", invalidToken("could not parse token: %v", err.Error())
+	}
+
+	if len(token.Headers) != 1 {
+		return "", invalidToken("token has to be signed exactly once")
+	}
+
+	header := token.Headers[0]
+	algorithm := jose.SignatureAlgorithm(header.Algorithm)
+	curve, ok := jwtAlgorithms[algorithm]
+	if !ok {
+		return "", invalidToken("unsupported signature algorithm %v", header.Algorithm)
+	}
+
+	key, err := validator.key(header.KeyID)
+	if err != nil {
+		return "", err
+	}
+
+	err = checkKeyAlgorithm(&key, algorithm, curve)
+	if err != nil {
+		return "", invalidToken("%v", err.Error())
+	}
+
+	claims := jwt.Claims{}
+	err = token.Claims(key.Key, &claims)
+	if err != nil {
+		return "", invalidToken("%v", err.Error())
+	}
+
+	err = validator.checkClaims(&claims)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+func (validator *JWTValidator) checkClaims(claims *jwt.Claims) error {
+	if claims.Expiry == nil {
+		return invalidToken("missing exp claim")
+	}
+
+	err := claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   validator.Issuer,
+		Audience: jwt.Audience{validator.Audience},
+		Time:     validator.now(),
+	}, validator.ClockSkew)
+	if err != nil {
+		return invalidToken("%v", err.Error())
+	}
+
+	if claims.Subject == "" {
+		return invalidToken("missing sub claim")
+	}
+
+	return nil
+}
+
+//checkKeyAlgorithm Checks that the key may be used with the algorithm of the token
+//ECDSA keys have to be on the curve of the algorithm, keys that name an algorithm are only used with that one
+func checkKeyAlgorithm(key *jose.JSONWebKey, algorithm jose.SignatureAlgorithm, curve elliptic.Curve) error {
+	if key.Algorithm != "" && key.Algorithm != string(algorithm) {
+		return fmt.Errorf("algorithm %v does not match algorithm %v of key %v", algorithm, key.Algorithm, key.KeyID)
+	}
+
+	switch publicKey := key.Key.(type) {
+	case *rsa.PublicKey:
+		if curve != nil {
+			return fmt.Errorf("algorithm %v does not match RSA key %v", algorithm, key.KeyID)
+		}
+	case *ecdsa.PublicKey:
+		if curve == nil || publicKey.Curve != curve {
+			return fmt.Errorf("algorithm %v does not match EC key %v", algorithm, key.KeyID)
+		}
+	default:
+		return fmt.Errorf("unsupported type of key %v", key.KeyID)
+	}
+
+	return nil
+}
+
+//key Returns the key with the given id
+//The key set is fetched again if it is outdated or the key is unknown, the latter at most once per MinRefreshInterval
+func (validator *JWTValidator) key(kid string) (jose.JSONWebKey, error) {
+	validator.mutex.RLock()
+	key, ok := lookupKey(validator.keys, kid)
+	outdated := validator.now().Sub(validator.lastFetch) > validator.RefreshInterval
+	refetchAllowed := validator.now().Sub(validator.lastFetch) > validator.MinRefreshInterval
+	validator.mutex.RUnlock()
+
+	if ok && !outdated {
+		return key, nil
+	}
+
+	if !ok && !outdated && !refetchAllowed {
+		return jose.JSONWebKey{}, invalidToken("unknown key id %v", kid)
+	}
+
+	_, err, _ := validator.fetchGroup.Do("jwks", func() (interface{}, error) {
+		return nil, validator.fetchKeys()
+	})
+	if err != nil {
+		log.Println(err.Error())
+		// An outdated key set is still used if the issuer is temporarily not reachable
+		if ok {
+			return key, nil
+		}
+		return jose.JSONWebKey{}, err
+	}
+
+	validator.mutex.RLock()
+	defer validator.mutex.RUnlock()
+
+	key, ok = lookupKey(validator.keys, kid)
+	if !ok {
+		return jose.JSONWebKey{}, invalidToken("unknown key id %v", kid)
+	}
+
+	return key, nil
+}
+
+//lookupKey Returns the key with the given id, tokens without a key id are only accepted if the key set contains a single key
+func lookupKey(keys map[string]jose.JSONWebKey, kid string) (jose.JSONWebKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+
+	key, ok := keys[kid]
+	return key, ok
+}
+
+//fetchKeys Fetches and replaces the key set of the issuer
+//Keys that can not be parsed, are not public or are not meant for signatures are skipped
+func (validator *JWTValidator) fetchKeys() error {
+	jwksURL := validator.JWKSURL
+	if jwksURL == "" {
+		discoveredURL, err := validator.discoverJWKSURL()
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		jwksURL = discoveredURL
+	}
+
+	keySet := struct {
+		Keys []json.RawMessage `json:"keys"`
+	}{}
+	err := validator.getJSON(jwksURL, &keySet)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	keys := make(map[string]jose.JSONWebKey)
+	for _, rawKey := range keySet.Keys {
+		key := jose.JSONWebKey{}
+		err := key.UnmarshalJSON(rawKey)
+		if err != nil {
+			log.Println(fmt.Sprintf("Skipping key of key set %v: %v", jwksURL, err.Error()))
+			continue
+		}
+
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		if !key.Valid() || !key.IsPublic() {
+			log.Println(fmt.Sprintf("Skipping key %v of key set %v: not a valid public key", key.KeyID, jwksURL))
+			continue
+		}
+
+		keys[key.KeyID] = key
+	}
+
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+
+	validator.keys = keys
+	validator.lastFetch = validator.now()
+
+	return nil
+}
+
+//discoverJWKSURL Reads the jwks_uri from the OpenID Connect discovery document of the issuer
+func (validator *JWTValidator) discoverJWKSURL() (string, error) {
+	discovery := struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+
+	err := validator.getJSON(strings.TrimSuffix(validator.Issuer, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	if discovery.Issuer != validator.Issuer {
+		return "", fmt.Errorf("Discovery document belongs to issuer %v instead of %v", discovery.Issuer, validator.Issuer)
+	}
+
+	if discovery.JWKSURI == "" {
+		return "", fmt.Errorf("Discovery document of issuer %v does not contain a jwks_uri", validator.Issuer)
+	}
+
+	return discovery.JWKSURI, nil
+}
+
+func (validator *JWTValidator) getJSON(url string, value interface{}) error {
+	response, err := validator.HTTPClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed requesting %v: %v", url, err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad response when requesting %v: %v", url, response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(value)
+	if err != nil {
+		return fmt.Errorf("failed decoding response of %v: %v", url, err.Error())
+	}
+
+	return nil
+}
+
+func invalidToken(format string, args ...interface{}) error {
+	return apierrors.New(apierrors.Unauthenticated, "Invalid access token: "+format, args...)
+}
@@ -0,0 +1,333 @@
+package authhandler
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	jose "github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/spf13/viper"
+)
+
+type testIssuer struct {
+	server   *httptest.Server
+	keys     map[string]crypto.Signer
+	requests int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{
+		keys: make(map[string]crypto.Signer),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issuer.requests, 1)
+
+		keySet := jose.JSONWebKeySet{}
+		for kid, key := range issuer.keys {
+			keySet.Keys = append(keySet.Keys, jose.JSONWebKey{
+				Key:   key.Public(),
+				KeyID: kid,
+				Use:   "sig",
+			})
+		}
+
+		json.NewEncoder(w).Encode(&keySet)
+	})
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (issuer *testIssuer) sign(t *testing.T, kid string, claims map[string]interface{}) string {
+	key := issuer.keys[kid]
+
+	alg := jose.RS256
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = jose.ES256
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key}, (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+//signRaw Builds a token with an arbitrary header, the signature is computed over the signing input by the given function
+func signRaw(t *testing.T, header map[string]interface{}, claims map[string]interface{}, signature func(signingInput []byte) []byte) string {
+	encodedHeader, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodedClaims, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(encodedHeader) + "." + base64.RawURLEncoding.EncodeToString(encodedClaims)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature([]byte(signingInput)))
+}
+
+func (issuer *testIssuer) validator() *JWTValidator {
+	return &JWTValidator{
+		Issuer:             issuer.server.URL,
+		Audience:           "sciobjsdb",
+		RefreshInterval:    time.Hour,
+		MinRefreshInterval: 0,
+		ClockSkew:          time.Minute,
+		HTTPClient:         issuer.server.Client(),
+		now:                time.Now,
+	}
+}
+
+func (issuer *testIssuer) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": issuer.server.URL,
+		"sub": "testuser",
+		"aud": []string{"account", "sciobjsdb"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestJWTValidator_UserID(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["rsa"] = rsaKey
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["ec"] = ecKey
+
+	validator := issuer.validator()
+
+	for _, kid := range []string{"rsa", "ec"} {
+		userID, err := validator.UserID(issuer.sign(t, kid, issuer.claims()))
+		if err != nil {
+			t.Fatalf("Valid token signed with key %v was rejected: %v", kid, err)
+		}
+
+		if userID != "testuser" {
+			t.Errorf("Expected user testuser, got %v", userID)
+		}
+	}
+
+	expiredClaims := issuer.claims()
+	expiredClaims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	wrongAudienceClaims := issuer.claims()
+	wrongAudienceClaims["aud"] = "otherservice"
+
+	wrongIssuerClaims := issuer.claims()
+	wrongIssuerClaims["iss"] = "https://issuer.invalid"
+
+	validToken := issuer.sign(t, "rsa", issuer.claims())
+
+	invalidTokens := map[string]string{
+		"expired":        issuer.sign(t, "rsa", expiredClaims),
+		"wrong audience": issuer.sign(t, "rsa", wrongAudienceClaims),
+		"wrong issuer":   issuer.sign(t, "rsa", wrongIssuerClaims),
+		"tampered":       validToken[:len(validToken)-4] + "AAAA",
+		"malformed":      "not-a-jwt",
+	}
+
+	for name, token := range invalidTokens {
+		_, err := validator.UserID(token)
+		if !apierrors.Is(err, apierrors.Unauthenticated) {
+			t.Errorf("Expected unauthenticated error for %v token, got %v", name, err)
+		}
+	}
+}
+
+func TestJWTValidator_RejectsForgedTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["rsa"] = rsaKey
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["ec"] = ecKey
+
+	validator := issuer.validator()
+
+	rsaPublicKey, err := x509.MarshalPKIXPublicKey(rsaKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signRS256 := func(signingInput []byte) []byte {
+		digest := sha256.Sum256(signingInput)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signature
+	}
+
+	invalidTokens := map[string]string{
+		"alg none": signRaw(t, map[string]interface{}{"alg": "none", "kid": "rsa"}, issuer.claims(), func(signingInput []byte) []byte {
+			return nil
+		}),
+		// The public key of the issuer is used as HMAC secret to forge a token
+		"HS256": signRaw(t, map[string]interface{}{"alg": "HS256", "kid": "rsa"}, issuer.claims(), func(signingInput []byte) []byte {
+			mac := hmac.New(sha256.New, rsaPublicKey)
+			mac.Write(signingInput)
+			return mac.Sum(nil)
+		}),
+		"RSA key with ES256": signRaw(t, map[string]interface{}{"alg": "ES256", "kid": "rsa"}, issuer.claims(), signRS256),
+		"EC key with RS256":  signRaw(t, map[string]interface{}{"alg": "RS256", "kid": "ec"}, issuer.claims(), signRS256),
+		// A valid ECDSA signature of the P-256 key padded to the signature size of ES384
+		"P-256 key with ES384": signRaw(t, map[string]interface{}{"alg": "ES384", "kid": "ec"}, issuer.claims(), func(signingInput []byte) []byte {
+			digest := sha512.Sum384(signingInput)
+			r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := make([]byte, 96)
+			r.FillBytes(signature[:48])
+			s.FillBytes(signature[48:])
+			return signature
+		}),
+		"unknown crit header": signRaw(t, map[string]interface{}{"alg": "RS256", "kid": "rsa", "crit": []string{"exp"}, "exp": 0}, issuer.claims(), signRS256),
+	}
+
+	for name, token := range invalidTokens {
+		_, err := validator.UserID(token)
+		if !apierrors.Is(err, apierrors.Unauthenticated) {
+			t.Errorf("Expected unauthenticated error for %v token, got %v", name, err)
+		}
+	}
+
+	validToken := signRaw(t, map[string]interface{}{"alg": "RS256", "kid": "rsa"}, issuer.claims(), signRS256)
+	_, err = validator.UserID(validToken)
+	if err != nil {
+		t.Errorf("Valid token built like the forged ones was rejected: %v", err)
+	}
+}
+
+func TestNewJWTValidator(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	viper.Set("Config.OAuth2Auth.JWT.Issuer", "https://issuer.example")
+
+	_, err := NewJWTValidator()
+	if err == nil {
+		t.Errorf("Expected validator without audience to be rejected")
+	}
+
+	viper.Set("Config.OAuth2Auth.JWT.Audience", "sciobjsdb")
+
+	validator, err := NewJWTValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if validator.Audience != "sciobjsdb" {
+		t.Errorf("Expected audience sciobjsdb, got %v", validator.Audience)
+	}
+}
+
+func TestJWTValidator_KeyRotation(t *testing.T) {
+	issuer := newTestIssuer(t)
+
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["old"] = oldKey
+
+	validator := issuer.validator()
+
+	_, err = validator.UserID(issuer.sign(t, "old", issuer.claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = validator.UserID(issuer.sign(t, "old", issuer.claims()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if issuer.requests != 1 {
+		t.Errorf("Expected the key set to be fetched once, got %v requests", issuer.requests)
+	}
+
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["new"] = newKey
+
+	_, err = validator.UserID(issuer.sign(t, "new", issuer.claims()))
+	if err != nil {
+		t.Fatalf("Token signed with rotated key was rejected: %v", err)
+	}
+
+	if issuer.requests != 2 {
+		t.Errorf("Expected the key set to be fetched again for an unknown key, got %v requests", issuer.requests)
+	}
+
+	validator.MinRefreshInterval = time.Hour
+
+	unknownKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.keys["unknown"] = unknownKey
+	token := issuer.sign(t, "unknown", issuer.claims())
+	delete(issuer.keys, "unknown")
+
+	_, err = validator.UserID(token)
+	if !apierrors.Is(err, apierrors.Unauthenticated) {
+		t.Errorf("Expected unauthenticated error for unknown key, got %v", err)
+	}
+
+	if issuer.requests != 2 {
+		t.Errorf("Key set was fetched again within the minimal refresh interval")
+	}
+}
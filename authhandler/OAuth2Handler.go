@@ -9,16 +9,49 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/spf13/viper"
 )
 
+//OAuth2ModeUserInfo Resolves access tokens by requesting the userinfo endpoint of the identity provider
+const OAuth2ModeUserInfo = "userinfo"
+
+//OAuth2ModeJWT Validates access tokens locally as JWTs signed by the identity provider
+const OAuth2ModeJWT = "jwt"
+
 //OAuth2Handler Handles oauth2
 type OAuth2Handler struct {
 	UserInfoEndpointURL string
+	UserInfoCache       *UserInfoCache
+	JWTValidator        *JWTValidator
 }
 
 // InitOauth2 Initializes the auth handler object
+// The mode is selected with Config.OAuth2Auth.Mode, either "userinfo" (default) or "jwt"
 func InitOauth2() (*OAuth2Handler, error) {
+	mode := viper.GetString("Config.OAuth2Auth.Mode")
+
+	switch mode {
+	case "", OAuth2ModeUserInfo:
+		return initUserInfoOAuth2()
+	case OAuth2ModeJWT:
+		validator, err := NewJWTValidator()
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		return &OAuth2Handler{
+			JWTValidator: validator,
+		}, nil
+	default:
+		err := fmt.Errorf("Unknown oauth2 mode %v in 'Config.OAuth2Auth.Mode'", mode)
+		log.Println(err.Error())
+		return nil, err
+	}
+}
+
+func initUserInfoOAuth2() (*OAuth2Handler, error) {
 	endpointURL := viper.GetString("Config.OAuth2Auth.UserInfoEndpoint")
 	if endpointURL == "" {
 		err := errors.New("Endpoint URL has to be provided in config as 'Config.OAuth2Auth.UserInfoEndpoint'")
@@ -26,13 +59,40 @@ func InitOauth2() (*OAuth2Handler, error) {
 		return nil, err
 	}
 
+	cacheTTL := viper.GetDuration("Config.OAuth2Auth.UserInfoCacheTTL")
+	if cacheTTL == 0 {
+		cacheTTL = defaultUserInfoCacheTTL
+	}
+
+	negativeCacheTTL := viper.GetDuration("Config.OAuth2Auth.UserInfoNegativeCacheTTL")
+	if negativeCacheTTL == 0 {
+		negativeCacheTTL = defaultUserInfoNegativeCacheTTL
+	}
+
 	oauth2Handler := OAuth2Handler{
 		UserInfoEndpointURL: endpointURL,
 	}
 
+	oauth2Handler.UserInfoCache = NewUserInfoCache(cacheTTL, negativeCacheTTL, oauth2Handler.getUserIDFromOAuth2)
+
+	cacheMaxEntries := viper.GetInt("Config.OAuth2Auth.UserInfoCacheMaxEntries")
+	if cacheMaxEntries > 0 {
+		oauth2Handler.UserInfoCache.MaxEntries = cacheMaxEntries
+	}
+
 	return &oauth2Handler, nil
 }
 
+//UserID Returns the user id of an oauth2 access token
+//Lookups at the userinfo endpoint are cached
+func (handler *OAuth2Handler) UserID(accessToken string) (string, error) {
+	if handler.JWTValidator != nil {
+		return handler.JWTValidator.UserID(accessToken)
+	}
+
+	return handler.UserInfoCache.UserID(accessToken)
+}
+
 func (handler *OAuth2Handler) getUserIDFromOAuth2(accessToken string) (string, error) {
 	req, err := http.NewRequest(
 		"GET",
@@ -51,6 +111,12 @@ func (handler *OAuth2Handler) getUserIDFromOAuth2(accessToken string) (string, e
 		return "", fmt.Errorf("failed getting user info: %s", err.Error())
 	}
 	defer response.Body.Close()
+	if response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden {
+		err := apierrors.New(apierrors.Unauthenticated, "access token was rejected when requesting userinfo: %v", response.Status)
+		log.Println(err)
+		return "", err
+	}
+
 	if response.StatusCode != http.StatusOK {
 		err := fmt.Errorf("bad reponse when requesting userinfo: %v", response.Status)
 		log.Println(err)
@@ -72,7 +138,7 @@ func (handler *OAuth2Handler) getUserIDFromOAuth2(accessToken string) (string, e
 	var ok bool
 	var userID interface{}
 	if userID, ok = parsedContents["sub"]; !ok {
-		return "", fmt.Errorf("Could not read sub claim from userinfo response")
+		return "", apierrors.New(apierrors.Unauthenticated, "Could not read sub claim from userinfo response")
 	}
 
 	userIDString := userID.(string)
@@ -2,16 +2,14 @@ package authhandler
 
 import (
 	"context"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
 	"github.com/ScienceObjectsDB/go-api/models"
 
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 const tokenLen = 64
@@ -66,48 +64,58 @@ func InitProjectHandler(
 
 }
 
+//UserID Returns the id of the user that sent the request
+//For API tokens this is the user that created the token
 func (handler *ProjectAuthHandler) UserID(requestContext context.Context) (string, error) {
 	requestToken, err := getToken(requestContext)
 	if err != nil {
 		log.Println(err.Error())
-		return "false", err
+		return "", err
 	}
 
-	userID, err := handler.OAuth2Handler.getUserIDFromOAuth2(requestToken.Token)
+	switch requestToken.TokenType {
+	case OAuth2Token:
+		userID, err := handler.OAuth2Handler.UserID(requestToken.Token)
+		if err != nil {
+			log.Println(err.Error())
+			return "", err
+		}
+
+		return userID, nil
+	case UserAPIToken:
+		token, err := handler.DatabaseTokenHandler.GetTokenUser(requestToken.Token)
+		if err != nil {
+			log.Println(err.Error())
+			return "", err
+		}
+
+		return token.GetUserID().GetUserID(), nil
+	default:
+		return "", apierrors.New(apierrors.Unauthenticated, "Could not process tokentype")
+	}
+}
+
+//TokenType Returns the type of the token the request was sent with, the token itself is not validated
+func (handler *ProjectAuthHandler) TokenType(requestContext context.Context) (TokenType, error) {
+	requestToken, err := getToken(requestContext)
 	if err != nil {
 		log.Println(err.Error())
-		return "false", err
+		return 0, err
 	}
 
-	return userID, nil
+	return requestToken.TokenType, nil
 }
 
 //Authorize Authorizes the request for a resource based on project scoped rights
+//OAuth2 tokens are authorized with the rights of their user in the project of the resource,
+//API tokens with their own rights if the resource is part of the project or dataset they are scoped to
 func (handler *ProjectAuthHandler) Authorize(
 	requestContext context.Context,
 	resource models.Resource,
 	requiredRight models.Right,
 	resourceID string) (bool, error) {
 
-	var err error
-	var projectID string
-
-	switch resource {
-	case models.Resource_Project:
-		projectID = resourceID
-	case models.Resource_Dataset:
-		projectID, err = handler.DatasetHandler.GetDatasetProjectID(resourceID)
-	case models.Resource_DatasetVersion:
-		projectID, err = handler.getDatasetVersionProjectID(resourceID)
-	case models.Resource_DatasetObjectGroupResource:
-		projectID, err = handler.getObjectGroupProjectID(resourceID)
-	case models.Resource_DatasetObject:
-		projectID, err = handler.getObjectProjectID(resourceID)
-	default:
-		err = fmt.Errorf("Can not process resource type: %v", resource)
-		return false, err
-	}
-
+	scope, err := handler.resourceScope(resource, resourceID)
 	if err != nil {
 		log.Println(err.Error())
 		return false, err
@@ -119,26 +127,25 @@ func (handler *ProjectAuthHandler) Authorize(
 		return false, err
 	}
 
-	userID, err := handler.OAuth2Handler.getUserIDFromOAuth2(requestToken.Token)
-	if err != nil {
-		log.Println(err.Error())
-		return false, err
-	}
-
 	var authorized bool
 
 	switch requestToken.TokenType {
 	case OAuth2Token:
-		authorized, err = handler.ProjectHandler.UserCanAccessProject(requiredRight, userID, projectID)
+		var userID string
+		userID, err = handler.OAuth2Handler.UserID(requestToken.Token)
+		if err != nil {
+			break
+		}
+		authorized, err = handler.ProjectHandler.UserCanAccessProject(requiredRight, userID, scope.ProjectID)
 	case UserAPIToken:
-		authorized, err = handler.DatabaseTokenHandler.ValidateTokenForResourceAction(requestToken.Token, projectID, models.Resource_Project, requiredRight)
+		authorized, err = handler.DatabaseTokenHandler.ValidateTokenForResourceAction(requestToken.Token, scope.ProjectID, scope.DatasetID, requiredRight)
 	default:
-		authorized, err = false, fmt.Errorf("Could not process tokentype")
+		authorized, err = false, apierrors.New(apierrors.Unauthenticated, "Could not process tokentype")
 	}
 
 	if err != nil {
 		log.Println(err.Error())
-		return false, nil
+		return false, err
 	}
 
 	return authorized, nil
@@ -147,7 +154,7 @@ func (handler *ProjectAuthHandler) Authorize(
 func getToken(ctx context.Context) (*ExtractedToken, error) {
 	meta, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Errorf(codes.Unauthenticated, "missing context metadata")
+		return nil, apierrors.New(apierrors.Unauthenticated, "missing context metadata")
 	}
 
 	accessToken := meta.Get("AccessToken")
@@ -162,45 +169,71 @@ func getToken(ctx context.Context) (*ExtractedToken, error) {
 		extractedToken.Token = apiToken[0]
 		extractedToken.TokenType = UserAPIToken
 	} else {
-		return nil, fmt.Errorf("Could not extract auth token, please specify access_token or user_api_token")
+		return nil, apierrors.New(apierrors.Unauthenticated, "Could not extract auth token, please specify access_token or user_api_token")
 	}
 
 	return &extractedToken, nil
 }
 
-func (handler *ProjectAuthHandler) getDatasetVersionProjectID(id string) (string, error) {
-	datasetID, err := handler.DatasetVersionHandler.GetDatasetVersionDatasetID(id)
+//resourceScope The project and dataset a resource belongs to
+//The dataset ID is empty for projects
+type resourceScope struct {
+	ProjectID string
+	DatasetID string
+}
+
+//resourceScope Resolves the project and dataset of a resource
+func (handler *ProjectAuthHandler) resourceScope(resource models.Resource, resourceID string) (*resourceScope, error) {
+	var err error
+	datasetID := resourceID
+
+	switch resource {
+	case models.Resource_Project:
+		return &resourceScope{ProjectID: resourceID}, nil
+	case models.Resource_Dataset:
+	case models.Resource_DatasetVersion:
+		datasetID, err = handler.DatasetVersionHandler.GetDatasetVersionDatasetID(resourceID)
+	case models.Resource_DatasetObjectGroupResource:
+		datasetID, err = handler.getObjectGroupDatasetID(resourceID)
+	case models.Resource_DatasetObject:
+		datasetID, err = handler.getObjectDatasetID(resourceID)
+	default:
+		return nil, apierrors.New(apierrors.InvalidArgument, "Can not process resource type: %v", resource)
+	}
+
 	if err != nil {
 		log.Println(err.Error())
-		return "", nil
+		return nil, err
 	}
-	return handler.DatasetHandler.GetDatasetProjectID(datasetID)
-}
 
-func (handler *ProjectAuthHandler) getObjectGroupProjectID(id string) (string, error) {
-	objectGroup, err := handler.ObjectGroupHandler.GetObjectGroup(id)
+	projectID, err := handler.DatasetHandler.GetDatasetProjectID(datasetID)
 	if err != nil {
 		log.Println(err.Error())
-		return "", nil
+		return nil, err
 	}
-	datasetID := objectGroup.GetDatasetID()
 
-	return handler.DatasetHandler.GetDatasetProjectID(datasetID)
+	return &resourceScope{
+		ProjectID: projectID,
+		DatasetID: datasetID,
+	}, nil
 }
 
-func (handler *ProjectAuthHandler) getObjectProjectID(id string) (string, error) {
-	groupID, _, err := handler.ObjectGroupHandler.GetObject(id)
+func (handler *ProjectAuthHandler) getObjectGroupDatasetID(id string) (string, error) {
+	objectGroup, err := handler.ObjectGroupHandler.GetObjectGroup(id)
 	if err != nil {
 		log.Println(err.Error())
-		return "", nil
+		return "", err
 	}
 
-	objectGroup, err := handler.ObjectGroupHandler.GetObjectGroup(groupID)
+	return objectGroup.GetDatasetID(), nil
+}
+
+func (handler *ProjectAuthHandler) getObjectDatasetID(id string) (string, error) {
+	groupID, _, err := handler.ObjectGroupHandler.GetObject(id)
 	if err != nil {
 		log.Println(err.Error())
-		return "", nil
+		return "", err
 	}
-	datasetID := objectGroup.GetDatasetID()
 
-	return handler.DatasetHandler.GetDatasetProjectID(datasetID)
+	return handler.getObjectGroupDatasetID(groupID)
 }
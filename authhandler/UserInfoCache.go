@@ -0,0 +1,138 @@
+package authhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"golang.org/x/sync/singleflight"
+)
+
+//defaultUserInfoCacheTTL Time a successful userinfo lookup is cached if none is configured
+const defaultUserInfoCacheTTL = 5 * time.Minute
+
+//defaultUserInfoNegativeCacheTTL Time a rejected token is cached if none is configured
+const defaultUserInfoNegativeCacheTTL = time.Minute
+
+//defaultUserInfoCacheMaxEntries Maximal number of cached tokens if none is configured
+const defaultUserInfoCacheMaxEntries = 10000
+
+//userInfoLookup Resolves an access token to the id of its user
+type userInfoLookup func(accessToken string) (string, error)
+
+type userInfoCacheEntry struct {
+	UserID  string
+	Err     error
+	Expires time.Time
+}
+
+//UserInfoCache Caches the results of userinfo lookups by the hash of the access token
+//Concurrent lookups of the same token are deduplicated, tokens that were rejected by the identity provider are cached as well
+//Other errors, e.g. failed requests to the identity provider, are not cached
+//At most MaxEntries tokens are cached, so requests with many distinct tokens can not exhaust the memory
+type UserInfoCache struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	MaxEntries  int
+	lookup      userInfoLookup
+	now         func() time.Time
+	mutex       sync.Mutex
+	entries     map[string]userInfoCacheEntry
+	lastSweep   time.Time
+	lookupGroup singleflight.Group
+}
+
+//NewUserInfoCache Creates a new cache in front of the given lookup function
+func NewUserInfoCache(ttl time.Duration, negativeTTL time.Duration, lookup userInfoLookup) *UserInfoCache {
+	return &UserInfoCache{
+		TTL:         ttl,
+		NegativeTTL: negativeTTL,
+		MaxEntries:  defaultUserInfoCacheMaxEntries,
+		lookup:      lookup,
+		now:         time.Now,
+		entries:     make(map[string]userInfoCacheEntry),
+	}
+}
+
+//UserID Returns the user id of the access token, either from the cache or by looking it up
+func (cache *UserInfoCache) UserID(accessToken string) (string, error) {
+	key := tokenHash(accessToken)
+
+	if entry, ok := cache.get(key); ok {
+		return entry.UserID, entry.Err
+	}
+
+	userID, err, _ := cache.lookupGroup.Do(key, func() (interface{}, error) {
+		// A lookup of the same token may have finished since the cache was checked
+		if entry, ok := cache.get(key); ok {
+			return entry.UserID, entry.Err
+		}
+
+		userID, err := cache.lookup(accessToken)
+
+		switch {
+		case err == nil:
+			cache.set(key, userInfoCacheEntry{UserID: userID, Expires: cache.now().Add(cache.TTL)})
+		case apierrors.Is(err, apierrors.Unauthenticated):
+			cache.set(key, userInfoCacheEntry{Err: err, Expires: cache.now().Add(cache.NegativeTTL)})
+		}
+
+		return userID, err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return userID.(string), nil
+}
+
+func (cache *UserInfoCache) get(key string) (userInfoCacheEntry, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	entry, ok := cache.entries[key]
+	if !ok || !cache.now().Before(entry.Expires) {
+		return userInfoCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (cache *UserInfoCache) set(key string, entry userInfoCacheEntry) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := cache.now()
+
+	// Expired entries are only removed from time to time to keep inserts cheap
+	if now.Sub(cache.lastSweep) > cache.TTL {
+		for cachedKey, cachedEntry := range cache.entries {
+			if !now.Before(cachedEntry.Expires) {
+				delete(cache.entries, cachedKey)
+			}
+		}
+
+		cache.lastSweep = now
+	}
+
+	// A full cache evicts arbitrary entries, Go randomizes the iteration order of maps
+	if _, ok := cache.entries[key]; !ok {
+		for cachedKey := range cache.entries {
+			if len(cache.entries) < cache.MaxEntries {
+				break
+			}
+
+			delete(cache.entries, cachedKey)
+		}
+	}
+
+	cache.entries[key] = entry
+}
+
+//tokenHash Returns the hex encoded sha256 hash of a token, so that no plain tokens are kept in memory
+func tokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,152 @@
+package authhandler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+)
+
+func TestUserInfoCache_UserID(t *testing.T) {
+	var lookups int32
+	now := time.Now()
+
+	cache := NewUserInfoCache(time.Minute, time.Second, func(accessToken string) (string, error) {
+		atomic.AddInt32(&lookups, 1)
+		switch accessToken {
+		case "valid":
+			return "testuser", nil
+		case "rejected":
+			return "", apierrors.New(apierrors.Unauthenticated, "token rejected")
+		default:
+			return "", errors.New("identity provider not reachable")
+		}
+	})
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		userID, err := cache.UserID("valid")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if userID != "testuser" {
+			t.Errorf("Expected user testuser, got %v", userID)
+		}
+	}
+
+	if lookups != 1 {
+		t.Errorf("Expected 1 lookup for a cached token, got %v", lookups)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := cache.UserID("rejected")
+		if !apierrors.Is(err, apierrors.Unauthenticated) {
+			t.Errorf("Expected unauthenticated error for rejected token, got %v", err)
+		}
+	}
+
+	if lookups != 2 {
+		t.Errorf("Rejected token was not cached, got %v lookups", lookups)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.UserID("unreachable")
+		if err == nil {
+			t.Errorf("Expected error for failed lookup")
+		}
+	}
+
+	if lookups != 4 {
+		t.Errorf("Failed lookups should not be cached, got %v lookups", lookups)
+	}
+
+	now = now.Add(2 * time.Second)
+
+	_, err := cache.UserID("rejected")
+	if err == nil {
+		t.Errorf("Expected error for rejected token")
+	}
+
+	_, err = cache.UserID("valid")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if lookups != 5 {
+		t.Errorf("Expected only the expired rejected token to be looked up again, got %v lookups", lookups)
+	}
+}
+
+func TestUserInfoCache_ConcurrentLookups(t *testing.T) {
+	const callers = 10
+
+	var lookups int32
+	var waiting sync.WaitGroup
+	waiting.Add(callers)
+
+	// The identity provider only answers once all callers are waiting for the token
+	cache := NewUserInfoCache(time.Minute, time.Minute, func(accessToken string) (string, error) {
+		atomic.AddInt32(&lookups, 1)
+		waiting.Wait()
+		return "testuser", nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			waiting.Done()
+			userID, err := cache.UserID("valid")
+			if err != nil {
+				t.Error(err)
+			}
+
+			if userID != "testuser" {
+				t.Errorf("Expected user testuser, got %v", userID)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if lookups != 1 {
+		t.Errorf("Expected concurrent lookups to be deduplicated, got %v lookups", lookups)
+	}
+}
+
+func TestUserInfoCache_MaxEntries(t *testing.T) {
+	var lookups int32
+
+	cache := NewUserInfoCache(time.Minute, time.Minute, func(accessToken string) (string, error) {
+		atomic.AddInt32(&lookups, 1)
+		return accessToken, nil
+	})
+	cache.MaxEntries = 10
+
+	for i := 0; i < 100; i++ {
+		_, err := cache.UserID(fmt.Sprintf("token%v", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(cache.entries) > cache.MaxEntries {
+			t.Fatalf("Cache holds %v entries, more than the maximum of %v", len(cache.entries), cache.MaxEntries)
+		}
+	}
+
+	_, err := cache.UserID("token99")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lookups != 100 {
+		t.Errorf("Expected the last token to stay cached, got %v lookups", lookups)
+	}
+}
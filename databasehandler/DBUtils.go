@@ -9,6 +9,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
@@ -33,6 +34,9 @@ const ProjectsCollectionsName = "Projects"
 //DatsetObjectGroupsCollectionName The Collection name for the dataset object groups
 const DatsetObjectGroupsCollectionName = "DatasetObjectGroups"
 
+//ObjectHeritageCollectionName The Collection name for the object heritages
+const ObjectHeritageCollectionName = "ObjectHeritages"
+
 // APITokenCollectionName The name of the mongo collection that stores the api tokens
 const APITokenCollectionName = "APITokens"
 
@@ -47,6 +51,7 @@ type DBUtilsHandler struct {
 	DatasetVersionCollName      string
 	DatasetObjectsCollName      string
 	DatasetObjectsGroupCollName string
+	ObjectHeritageCollName      string
 	AuthProjectCollectionName   string
 }
 
@@ -62,6 +67,7 @@ func NewDBUtilsHandler(client *mongo.Client, ctx context.Context) (*DBUtilsHandl
 		APITokenCollectionName:      "APIToken",
 		DatasetObjectsCollName:      "Objects",
 		DatasetObjectsGroupCollName: "ObjectGroups",
+		ObjectHeritageCollName:      "ObjectHeritages",
 		AuthProjectCollectionName:   "AuthProjects",
 	}
 
@@ -88,6 +94,11 @@ func (handler *DBUtilsHandler) GetDatasetObjectGroupCollection() *mongo.Collecti
 	return handler.GetManagementDatabase().Collection(handler.DatasetObjectsGroupCollName)
 }
 
+//GetObjectHeritageCollection Returns the collection that stores the object heritage entries
+func (handler *DBUtilsHandler) GetObjectHeritageCollection() *mongo.Collection {
+	return handler.GetManagementDatabase().Collection(handler.ObjectHeritageCollName)
+}
+
 // GetManagementDatabase Returns a handler to the default mongodb management database
 func (handler *DBUtilsHandler) GetManagementDatabase() *mongo.Database {
 	return handler.MongoClient.Database(handler.DatasetDatabaseName)
@@ -103,6 +114,88 @@ func (handler *DBUtilsHandler) GetTokenCollection() *mongo.Collection {
 	return handler.MongoClient.Database(handler.AuthDatabaseName).Collection(handler.APITokenCollectionName)
 }
 
+//maxTransactionAttempts The number of times a transaction is attempted before a transient transaction error is returned
+const maxTransactionAttempts = 5
+
+//TransactionFunc The database actions of a transaction
+//All actions have to use the given handler, its MongoDefaultContext binds them to the transaction
+type TransactionFunc func(handler *DBUtilsHandler) error
+
+//RunTransaction Runs the given actions in a multi-document transaction that is committed if they succeed and aborted otherwise
+//The actions are run again if the transaction fails with a transient transaction error, e.g. a write conflict with
+//a concurrent transaction, so they must not have side effects outside of the database
+//Actions of a handler that already runs in a transaction join it, the outermost transaction commits and retries them
+//Transactions require MongoDB to run as a replica set or sharded cluster
+func (handler *DBUtilsHandler) RunTransaction(transaction TransactionFunc) error {
+	if mongo.SessionFromContext(handler.MongoDefaultContext) != nil {
+		return transaction(handler)
+	}
+
+	session, err := handler.MongoClient.StartSession()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	defer session.EndSession(handler.MongoDefaultContext)
+
+	for attempt := 1; ; attempt++ {
+		_, err = session.WithTransaction(handler.MongoDefaultContext, func(sessionContext mongo.SessionContext) (interface{}, error) {
+			transactionHandler := *handler
+			transactionHandler.MongoDefaultContext = sessionContext
+
+			return nil, transaction(&transactionHandler)
+		})
+		// WithTransaction itself only retries transient errors of commands, not those of write operations
+		if err == nil || attempt == maxTransactionAttempts || !isTransientTransactionError(err) {
+			break
+		}
+
+		log.Infof("Retrying transaction after transient error: %v", err.Error())
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//CheckTransactionSupport Returns an error if the MongoDB deployment does not support multi-document transactions
+//Transactions are only supported by replica sets and sharded clusters, not by standalone servers
+func (handler *DBUtilsHandler) CheckTransactionSupport() error {
+	deployment := bson.M{}
+
+	err := handler.MongoClient.Database("admin").RunCommand(handler.MongoDefaultContext, bson.D{{Key: "isMaster", Value: 1}}).Decode(&deployment)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	// Members of a replica set report the name of their set, the routers of a sharded cluster identify themselves with isdbgrid
+	_, isReplicaSet := deployment["setName"]
+	if !isReplicaSet && deployment["msg"] != "isdbgrid" {
+		return errors.New("MongoDB has to run as a replica set or sharded cluster, standalone servers do not support transactions")
+	}
+
+	return nil
+}
+
+//isTransientTransactionError Checks whether a failed transaction can be retried as a whole
+func isTransientTransactionError(err error) bool {
+	var serverError mongo.ServerError
+	return errors.As(err, &serverError) && serverError.HasErrorLabel("TransientTransactionError")
+}
+
+//cursorNotFoundErrorCode The error code of the database if a cursor was discarded, e.g. after it was idle for too long
+const cursorNotFoundErrorCode = 43
+
+//isCursorNotFoundError Checks whether a query failed because the database discarded its cursor
+func isCursorNotFoundError(err error) bool {
+	var serverError mongo.ServerError
+	return errors.As(err, &serverError) && serverError.HasErrorCode(cursorNotFoundErrorCode)
+}
+
 //Insert Inserts a given value into a given collection and decodes the inserted value into the given decode value
 func (handler *DBUtilsHandler) Insert(collection *mongo.Collection, insertValue interface{}, decodeValue interface{}) error {
 	insertedResult, err := collection.InsertOne(handler.MongoDefaultContext, &insertValue)
@@ -232,3 +325,21 @@ func (handler *DBUtilsHandler) parseInsertResult(insertResult *mongo.InsertOneRe
 
 	return nil
 }
+
+//alreadyExistsError Converts a duplicate key error into an already exists error with the given message, other errors are returned unchanged
+func alreadyExistsError(err error, format string, args ...interface{}) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return apierrors.New(apierrors.AlreadyExists, format, args...)
+	}
+
+	return err
+}
+
+//notFoundError Converts mongo.ErrNoDocuments into a not found error for the given resource type, other errors are returned unchanged
+func notFoundError(err error, resourceType string, id string) error {
+	if err == mongo.ErrNoDocuments {
+		return apierrors.NewNotFound(resourceType, id)
+	}
+
+	return err
+}
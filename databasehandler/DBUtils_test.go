@@ -0,0 +1,90 @@
+package databasehandler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDBUtilsHandler_CheckTransactionSupport(t *testing.T) {
+	err := dbHandler.CheckTransactionSupport()
+	if err != nil {
+		t.Errorf("Test deployment was reported to not support transactions: %v", err)
+	}
+}
+
+func TestDBUtilsHandler_RunTransaction(t *testing.T) {
+	heritageID := uuid.New().String()
+
+	err := dbHandler.RunTransaction(func(handler *DBUtilsHandler) error {
+		_, err := handler.GetObjectHeritageCollection().InsertOne(handler.MongoDefaultContext, &models.ObjectHeritage{
+			ID: heritageID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return errors.New("abort")
+	})
+	if err == nil {
+		t.Errorf("Error of the transaction was not returned")
+	}
+
+	count, err := dbHandler.GetObjectHeritageCollection().CountDocuments(dbHandler.MongoDefaultContext, bson.M{"ID": heritageID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Errorf("Insert of an aborted transaction was committed")
+	}
+
+	attempts := 0
+
+	err = dbHandler.RunTransaction(func(handler *DBUtilsHandler) error {
+		attempts++
+
+		_, err := handler.GetObjectHeritageCollection().InsertOne(handler.MongoDefaultContext, &models.ObjectHeritage{
+			ID: heritageID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if attempts == 1 {
+			return mongo.WriteException{Labels: []string{"TransientTransactionError"}}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected the transaction to be retried once after a transient error, attempts: %v", attempts)
+	}
+
+	count, err = dbHandler.GetObjectHeritageCollection().CountDocuments(dbHandler.MongoDefaultContext, bson.M{"ID": heritageID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected the retried transaction to insert exactly one entry, found %v", count)
+	}
+}
+
+func TestIsCursorNotFoundError(t *testing.T) {
+	if !isCursorNotFoundError(mongo.CommandError{Code: cursorNotFoundErrorCode, Name: "CursorNotFound"}) {
+		t.Errorf("Discarded cursor was not detected")
+	}
+
+	if isCursorNotFoundError(mongo.CommandError{Code: 11000}) || isCursorNotFoundError(errors.New("other error")) || isCursorNotFoundError(nil) {
+		t.Errorf("Other error was reported as discarded cursor")
+	}
+}
@@ -1,11 +1,13 @@
 package databasehandler
 
 import (
-	"context"
+	"encoding/json"
 	"errors"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
 
@@ -13,9 +15,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+//mutableDatasetFields Fields of a dataset that can be updated after creation along with the parser for their string representation
+//Labels are given as JSON array of objects with a Key and a Value and replace all labels of the dataset
+//Datasets have no additional metadata in the API, so there is no AdditionalMetadata field to update
+var mutableDatasetFields = map[string]func(value string) (interface{}, error){
+	"Datasetname": parseNonEmptyString,
+	"Datasettype": parseNonEmptyString,
+	"Description": func(value string) (interface{}, error) {
+		return value, nil
+	},
+	"IsPublic": func(value string) (interface{}, error) {
+		return strconv.ParseBool(value)
+	},
+	"Labels": parseLabels,
+}
+
 //DatasetActionHandler Handler for dataset related database actions
 type DatasetActionHandler struct {
 	*DBUtilsHandler
@@ -31,6 +49,7 @@ func NewDatasetHandler(dbUtilsHandler *DBUtilsHandler) (*DatasetActionHandler, e
 }
 
 // CreateNewDataset Creates and inserts a new dataset
+// The name of a dataset has to be unique within its project
 func (handler *DatasetActionHandler) CreateNewDataset(request *services.CreateDatasetRequest) (*models.DatasetEntry, error) {
 	uuidString := uuid.New().String()
 
@@ -47,7 +66,7 @@ func (handler *DatasetActionHandler) CreateNewDataset(request *services.CreateDa
 	insertedResult, err := handler.GetDatasetCollection().InsertOne(handler.MongoDefaultContext, &datasetEntry)
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, alreadyExistsError(err, "Project %v already contains a dataset named %v", request.GetProjectID(), request.GetDatasetName())
 	}
 
 	var oid primitive.ObjectID
@@ -81,13 +100,9 @@ func (handler *DatasetActionHandler) GetDataset(datasetID string) (*models.Datas
 		"ID": datasetID,
 	})
 
-	if queryResult.Err() != nil && queryResult.Err() != mongo.ErrNoDocuments {
+	if queryResult.Err() != nil {
 		log.Println(queryResult.Err().Error())
-		return nil, queryResult.Err()
-	}
-
-	if queryResult.Err() == mongo.ErrNoDocuments {
-		return &models.DatasetEntry{}, nil
+		return nil, notFoundError(queryResult.Err(), models.Resource_Dataset.String(), datasetID)
 	}
 
 	var datasetEntry models.DatasetEntry
@@ -101,10 +116,177 @@ func (handler *DatasetActionHandler) GetDataset(datasetID string) (*models.Datas
 	return &datasetEntry, nil
 }
 
+// UpdateDatasetFields Updates the given fields of a dataset in a single atomic operation and returns the updated entry
+// Only the fields in mutableDatasetFields can be updated, the values are given as their string representation
+func (handler *DatasetActionHandler) UpdateDatasetFields(datasetID string, fields map[string]string) (*models.DatasetEntry, error) {
+	if len(fields) == 0 {
+		return nil, apierrors.New(apierrors.InvalidArgument, "At least one field has to be provided for an update")
+	}
+
+	updatedFields := bson.M{}
+
+	for field, value := range fields {
+		parseField, ok := mutableDatasetFields[field]
+		if !ok {
+			err := apierrors.New(apierrors.InvalidArgument, "Field %v of a dataset can not be updated", field)
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		parsedValue, err := parseField(value)
+		if err != nil {
+			err := apierrors.New(apierrors.InvalidArgument, "Invalid value for field %v: %v", field, err.Error())
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		updatedFields[field] = parsedValue
+	}
+
+	result := handler.GetDatasetCollection().FindOneAndUpdate(handler.MongoDefaultContext,
+		bson.M{"ID": datasetID},
+		bson.M{"$set": updatedFields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	)
+
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		err := notFoundError(result.Err(), models.Resource_Dataset.String(), datasetID)
+		return nil, alreadyExistsError(err, "The project of dataset %v already contains a dataset named %v", datasetID, fields["Datasetname"])
+	}
+
+	datasetEntry := models.DatasetEntry{}
+
+	err := result.Decode(&datasetEntry)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &datasetEntry, nil
+}
+
 // DeleteDataset Deletes a given dataset
-// Datasets should only be deleted if no data objects and dataset versions are associated with this project
-func (handler *DatasetActionHandler) DeleteDataset(datasetid string) error {
-	_, err := handler.GetDatasetCollection().DeleteOne(handler.MongoDefaultContext, bson.M{
+// Datasets are only deleted if no data objects and dataset versions are associated with them, unless cascade is set
+// With cascade set all dataset versions and object groups of the dataset are deleted along with it
+// Object heritages of the dataset are always deleted
+// The dataset is marked as deleting first, entries are only created in datasets that are not deleting and the creation
+// writes the dataset entry in its transaction as well, see reserveDatasetWrite, so a concurrent creation either
+// conflicts with the deletion or is rejected
+// Without cascade the marking, the check and the deletion run in one transaction, a dataset that is not empty stays unchanged
+// With cascade the dataset is marked as deleting and its entries are removed one collection after another,
+// the dataset entry itself is removed last, an interrupted deletion can be resumed by calling DeleteDataset again
+// Objects in the object storage have to be removed separately
+func (handler *DatasetActionHandler) DeleteDataset(datasetid string, cascade bool) error {
+	if !cascade {
+		return handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+			datasetHandler := DatasetActionHandler{
+				DBUtilsHandler: transactionHandler,
+			}
+
+			err := datasetHandler.markDatasetDeleting(datasetid)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = datasetHandler.CheckDatasetIsEmpty(datasetid)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			return datasetHandler.deleteDatasetEntries(datasetid)
+		})
+	}
+
+	err := handler.markDatasetDeleting(datasetid)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return handler.deleteDatasetEntries(datasetid)
+}
+
+//markDatasetDeleting Sets the status of a dataset to deleting, so no further entries are created in it
+func (handler *DatasetActionHandler) markDatasetDeleting(datasetid string) error {
+	_, err := handler.GetDatasetCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{"ID": datasetid},
+		bson.M{"$set": bson.M{"Status": models.Status_Deleting}},
+	)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//reserveDatasetWrite Fails if the dataset does not exist or is being deleted, otherwise increments its Writes counter
+//It has to run in the transaction that creates an entry of the dataset, the write to the dataset entry lets the transaction
+//conflict with a concurrent deletion of the dataset, which marks the dataset entry as deleting first
+func (handler *DBUtilsHandler) reserveDatasetWrite(datasetID string) error {
+	result := handler.GetDatasetCollection().FindOneAndUpdate(handler.MongoDefaultContext,
+		bson.M{"ID": datasetID},
+		bson.M{"$inc": bson.M{"Writes": int64(1)}},
+	)
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return notFoundError(result.Err(), models.Resource_Dataset.String(), datasetID)
+	}
+
+	dataset := models.DatasetEntry{}
+
+	err := result.Decode(&dataset)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if dataset.GetStatus() == models.Status_Deleting {
+		return apierrors.New(apierrors.FailedPrecondition, "Dataset %v is being deleted", datasetID)
+	}
+
+	return nil
+}
+
+//deleteDatasetEntries Deletes the object groups, dataset versions, object heritages and revision counters of a dataset
+//and finally the dataset entry itself
+func (handler *DatasetActionHandler) deleteDatasetEntries(datasetid string) error {
+	_, err := handler.GetDatasetObjectGroupCollection().DeleteMany(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	_, err = handler.GetDatasetVersionCollection().DeleteMany(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	_, err = handler.GetObjectHeritageCollection().DeleteMany(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	_, err = handler.GetDatasetVersionRevisionCollection().DeleteMany(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	_, err = handler.GetDatasetCollection().DeleteOne(handler.MongoDefaultContext, bson.M{
 		"ID": datasetid,
 	})
 	if err != nil {
@@ -115,6 +297,31 @@ func (handler *DatasetActionHandler) DeleteDataset(datasetid string) error {
 	return nil
 }
 
+// CheckDatasetIsEmpty Returns an error if dataset versions or object groups still reference the dataset
+func (handler *DatasetActionHandler) CheckDatasetIsEmpty(datasetid string) error {
+	versionCount, err := handler.GetDatasetVersionCollection().CountDocuments(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	objectGroupCount, err := handler.GetDatasetObjectGroupCollection().CountDocuments(handler.MongoDefaultContext, bson.M{
+		"DatasetID": datasetid,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if versionCount != 0 || objectGroupCount != 0 {
+		return apierrors.New(apierrors.FailedPrecondition, "Dataset %v still has %v dataset versions and %v object groups associated with it", datasetid, versionCount, objectGroupCount)
+	}
+
+	return nil
+}
+
 //GetDatasetProjectID Returns the project id of the dataset with the provided id
 func (handler *DatasetActionHandler) GetDatasetProjectID(datasetid string) (string, error) {
 	entry, err := handler.GetDataset(datasetid)
@@ -126,23 +333,47 @@ func (handler *DatasetActionHandler) GetDatasetProjectID(datasetid string) (stri
 	return entry.GetProjectID(), nil
 }
 
-func (handler *DatasetActionHandler) GetDatasetVersions(datasetid string) ([]*models.DatasetVersionEntry, error) {
+//GetDatasetVersions Returns a page of the versions of a dataset and the token of the next page
+func (handler *DatasetActionHandler) GetDatasetVersions(datasetid string, page *PageRequest) ([]*models.DatasetVersionEntry, string, error) {
 	var entries []*models.DatasetVersionEntry
 
-	queryResult, err := handler.GetDatasetVersionCollection().Find(handler.MongoDefaultContext, bson.M{
+	nextPageToken, err := handler.findPage(handler.GetDatasetVersionCollection(), bson.M{
 		"DatasetID": datasetid,
+	}, page, func(csr *mongo.Cursor) (string, error) {
+		entry := models.DatasetVersionEntry{}
+		err := csr.Decode(&entry)
+		entries = append(entries, &entry)
+		return entry.GetID(), err
 	})
-
 	if err != nil {
 		log.Println(err.Error())
-		return entries, err
+		return nil, "", err
+	}
+
+	return entries, nextPageToken, nil
+}
+
+func parseNonEmptyString(value string) (interface{}, error) {
+	if value == "" {
+		return nil, errors.New("value must not be empty")
 	}
 
-	err = queryResult.All(context.Background(), &entries)
+	return value, nil
+}
+
+func parseLabels(value string) (interface{}, error) {
+	labels := []*models.Label{}
+
+	err := json.Unmarshal([]byte(value), &labels)
 	if err != nil {
-		log.Println(err.Error())
 		return nil, err
 	}
 
-	return entries, nil
+	for _, label := range labels {
+		if label.GetKey() == "" {
+			return nil, errors.New("label keys must not be empty")
+		}
+	}
+
+	return labels, nil
 }
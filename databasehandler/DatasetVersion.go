@@ -27,29 +27,45 @@ func NewDatasetVersionHandler(dbHandler *DBUtilsHandler) (*DatasetVersionActionH
 }
 
 //ReleaseDatasetVersion Releases a new dataset version
+//The revision is taken from an atomic counter per version number and stage of the dataset, so concurrent releases get
+//distinct revisions, the counter is incremented in the transaction of the insert, so a failed insert does not leave a gap
 func (handler *DatasetVersionActionHandler) ReleaseDatasetVersion(request *services.ReleaseDatasetVersionRequest) (*models.DatasetVersionEntry, error) {
-	csr, err := handler.GetDatasetVersionCollection().Find(handler.MongoDefaultContext, bson.M{
-		"DatasetID":     request.GetDatasetID(),
-		"Version.Major": request.GetVersion().GetMajor(),
-		"Version.Minor": request.GetVersion().GetMinor(),
-		"Version.Patch": request.GetVersion().GetPatch(),
-		"Version.Stage": request.GetVersion().GetStage(),
-	})
+	var datasetVersion *models.DatasetVersionEntry
+
+	err := handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+		versionHandler := DatasetVersionActionHandler{
+			DBUtilsHandler: *transactionHandler,
+		}
 
+		var err error
+		datasetVersion, err = versionHandler.insertDatasetVersion(request)
+		return err
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	var revisionNumber int32
-	revisionNumber = 1
+	return datasetVersion, nil
+}
 
-	for csr.Next(handler.MongoDefaultContext) {
-		revisionNumber++
+//insertDatasetVersion Inserts a new dataset version with the next revision of its version number and stage
+//Fails if the dataset does not exist or is being deleted
+func (handler *DatasetVersionActionHandler) insertDatasetVersion(request *services.ReleaseDatasetVersionRequest) (*models.DatasetVersionEntry, error) {
+	err := handler.reserveDatasetWrite(request.GetDatasetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	revisionNumber, err := handler.nextRevision(request.GetDatasetID(), request.GetVersion())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
 	actualVersion := request.GetVersion()
-	actualVersion.Revision = int32(revisionNumber)
+	actualVersion.Revision = revisionNumber
 
 	uuidString := uuid.New().String()
 
@@ -68,7 +84,7 @@ func (handler *DatasetVersionActionHandler) ReleaseDatasetVersion(request *servi
 	result, err := handler.GetDatasetVersionCollection().InsertOne(handler.MongoDefaultContext, &datasetversionEntry)
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, alreadyExistsError(err, "Revision %v of the dataset version already exists", revisionNumber)
 	}
 
 	var oid primitive.ObjectID
@@ -108,7 +124,7 @@ func (handler *DatasetVersionActionHandler) GetDatasetVersion(id string) (*model
 
 	if result.Err() != nil {
 		log.Println(result.Err().Error())
-		return nil, result.Err()
+		return nil, notFoundError(result.Err(), models.Resource_DatasetVersion.String(), id)
 	}
 
 	err := result.Decode(&datasetVersionEntry)
@@ -0,0 +1,147 @@
+package databasehandler
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/go-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//DatasetVersionRevisionCollectionName The name of the mongo collection that stores the revision counters of the dataset versions
+const DatasetVersionRevisionCollectionName = "DatasetVersionRevisions"
+
+//datasetVersionRevision The last revision that was released for a version number and stage of a dataset
+type datasetVersionRevision struct {
+	DatasetID string                      `json:"DatasetID"`
+	Major     int32                       `json:"Major"`
+	Minor     int32                       `json:"Minor"`
+	Patch     int32                       `json:"Patch"`
+	Stage     models.Version_VersionStage `json:"Stage"`
+	Revision  int32                       `json:"Revision"`
+}
+
+//datasetVersionRevisionIndex The unique index that guarantees a single revision counter per version number and stage of a dataset
+var datasetVersionRevisionIndex = mongo.IndexModel{
+	Keys: bson.D{
+		{Key: "DatasetID", Value: 1},
+		{Key: "Major", Value: 1},
+		{Key: "Minor", Value: 1},
+		{Key: "Patch", Value: 1},
+		{Key: "Stage", Value: 1},
+	},
+	Options: options.Index().SetName("DatasetID_Major_Minor_Patch_Stage").SetUnique(true),
+}
+
+//datasetVersionIndex The unique index that guarantees that every revision of a version number and stage of a dataset is released once
+var datasetVersionIndex = mongo.IndexModel{
+	Keys: bson.D{
+		{Key: "DatasetID", Value: 1},
+		{Key: "Version.Major", Value: 1},
+		{Key: "Version.Minor", Value: 1},
+		{Key: "Version.Patch", Value: 1},
+		{Key: "Version.Stage", Value: 1},
+		{Key: "Version.Revision", Value: 1},
+	},
+	Options: options.Index().SetName("DatasetID_Version").SetUnique(true),
+}
+
+//GetDatasetVersionRevisionCollection Returns the collection that stores the revision counters of the dataset versions
+func (handler *DBUtilsHandler) GetDatasetVersionRevisionCollection() *mongo.Collection {
+	return handler.GetManagementDatabase().Collection(DatasetVersionRevisionCollectionName)
+}
+
+//revisionFilter Matches the revision counter of a version number and stage of a dataset
+func revisionFilter(datasetID string, version *models.Version) bson.M {
+	return bson.M{
+		"DatasetID": datasetID,
+		"Major":     version.GetMajor(),
+		"Minor":     version.GetMinor(),
+		"Patch":     version.GetPatch(),
+		"Stage":     version.GetStage(),
+	}
+}
+
+//nextRevision Atomically increments and returns the revision counter of a version number and stage of a dataset
+//The first release of a version number and stage gets revision 1
+func (handler *DBUtilsHandler) nextRevision(datasetID string, version *models.Version) (int32, error) {
+	updateOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	update := bson.M{"$inc": bson.M{"Revision": int32(1)}}
+
+	result := handler.GetDatasetVersionRevisionCollection().FindOneAndUpdate(handler.MongoDefaultContext, revisionFilter(datasetID, version), update, updateOptions)
+	// Concurrent upserts of a new counter can collide on the unique index, the counter exists once one of them succeeded
+	if mongo.IsDuplicateKeyError(result.Err()) {
+		result = handler.GetDatasetVersionRevisionCollection().FindOneAndUpdate(handler.MongoDefaultContext, revisionFilter(datasetID, version), update, updateOptions)
+	}
+
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return 0, result.Err()
+	}
+
+	revision := datasetVersionRevision{}
+
+	err := result.Decode(&revision)
+	if err != nil {
+		log.Println(err.Error())
+		return 0, err
+	}
+
+	return revision.Revision, nil
+}
+
+//migrateDatasetVersionRevisions Initializes the revision counters with the highest revision of the already released dataset versions
+func migrateDatasetVersionRevisions(handler *DBUtilsHandler) error {
+	_, err := handler.GetDatasetVersionRevisionCollection().Indexes().CreateOne(handler.MongoDefaultContext, datasetVersionRevisionIndex)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	csr, err := handler.GetDatasetVersionCollection().Aggregate(handler.MongoDefaultContext, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"DatasetID": "$DatasetID",
+				"Major":     "$Version.Major",
+				"Minor":     "$Version.Minor",
+				"Patch":     "$Version.Patch",
+				"Stage":     "$Version.Stage",
+			},
+			"Revision": bson.M{"$max": "$Version.Revision"},
+		}}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$_id", bson.M{"Revision": "$Revision"}}}}},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	var revisions []*datasetVersionRevision
+
+	err = csr.All(handler.MongoDefaultContext, &revisions)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	for _, revision := range revisions {
+		_, err := handler.GetDatasetVersionRevisionCollection().UpdateOne(handler.MongoDefaultContext, bson.M{
+			"DatasetID": revision.DatasetID,
+			"Major":     revision.Major,
+			"Minor":     revision.Minor,
+			"Patch":     revision.Patch,
+			"Stage":     revision.Stage,
+		}, bson.M{
+			"$max": bson.M{"Revision": revision.Revision},
+		}, options.Update().SetUpsert(true))
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	log.Infof("Initialized %v dataset version revision counters", len(revisions))
+
+	return nil
+}
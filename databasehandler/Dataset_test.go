@@ -3,14 +3,18 @@ package databasehandler
 import (
 	"context"
 	"os"
+	"sync"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/util"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 var dbHandler *DBUtilsHandler
@@ -46,9 +50,33 @@ func setup() error {
 		return err
 	}
 
+	err = dbHandler.Migrate()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
+//createTestDataset Creates a dataset in a new project for tests that create entries of a dataset
+func createTestDataset(t *testing.T, name string) *models.DatasetEntry {
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: name,
+		Datatype:    "txt",
+		ProjectID:   uuid.New().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dataset
+}
+
 func Test_Dataset(t *testing.T) {
 	datasetHandler, err := NewDatasetHandler(dbHandler)
 	if err != nil {
@@ -58,7 +86,7 @@ func Test_Dataset(t *testing.T) {
 	datasetRequest := services.CreateDatasetRequest{
 		DatasetName: "test123",
 		Datatype:    "txt",
-		ProjectID:   "145",
+		ProjectID:   uuid.New().String(),
 	}
 
 	entry, err := datasetHandler.CreateNewDataset(&datasetRequest)
@@ -94,7 +122,7 @@ func TestDatasetVersions(t *testing.T) {
 	datasetRequest := services.CreateDatasetRequest{
 		DatasetName: "test123",
 		Datatype:    "txt",
-		ProjectID:   "145",
+		ProjectID:   uuid.New().String(),
 	}
 
 	entry, err := datasetHandler.CreateNewDataset(&datasetRequest)
@@ -160,7 +188,7 @@ func TestDatasetVersions(t *testing.T) {
 		t.Error(err)
 	}
 
-	versionEntries, err := datasetHandler.GetDatasetVersions(entry.GetID())
+	versionEntries, _, err := datasetHandler.GetDatasetVersions(entry.GetID(), nil)
 	if err != nil {
 		t.Error(err)
 	}
@@ -170,3 +198,352 @@ func TestDatasetVersions(t *testing.T) {
 	}
 
 }
+
+func TestDatasetActionHandler_DeleteDataset(t *testing.T) {
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	datasetVersionHandler, err := NewDatasetVersionHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "deletetest",
+		Datatype:    "txt",
+		ProjectID:   uuid.New().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetVersionHandler.ReleaseDatasetVersion(&services.ReleaseDatasetVersionRequest{
+		Name:      "foo",
+		DatasetID: entry.GetID(),
+		Version: &models.Version{
+			Major: 1,
+			Stage: models.Version_Stable,
+		},
+		ObjectGroupIDs: make([]string, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = datasetHandler.DeleteDataset(entry.GetID(), false)
+	if err == nil {
+		t.Errorf("Dataset with associated versions was deleted without cascade")
+	}
+
+	dataset, err := datasetHandler.GetDataset(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dataset.GetStatus() == models.Status_Deleting {
+		t.Errorf("Dataset stayed marked as deleting after a rejected delete")
+	}
+
+	err = datasetHandler.DeleteDataset(entry.GetID(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versionEntries, _, err := datasetHandler.GetDatasetVersions(entry.GetID(), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(versionEntries) != 0 {
+		t.Errorf("Found %v dataset versions after cascading delete", len(versionEntries))
+	}
+
+	_, err = datasetHandler.GetDataset(entry.GetID())
+	if !apierrors.Is(err, apierrors.NotFound) {
+		t.Errorf("Expected not found error for deleted dataset %v, got: %v", entry.GetID(), err)
+	}
+}
+
+func TestDatasetActionHandler_CreateInDeletingDataset(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	dataset := createTestDataset(t, "deletingdataset")
+
+	_, err := dbHandler.GetDatasetCollection().UpdateOne(dbHandler.MongoDefaultContext,
+		bson.M{"ID": dataset.GetID()},
+		bson.M{"$set": bson.M{"Status": models.Status_Deleting}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "late",
+		DatasetID: dataset.GetID(),
+	}, dataset.GetProjectID())
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for an object group of a deleting dataset, got: %v", err)
+	}
+
+	datasetVersionHandler, err := NewDatasetVersionHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetVersionHandler.ReleaseDatasetVersion(&services.ReleaseDatasetVersionRequest{
+		Name:      "late",
+		DatasetID: dataset.GetID(),
+		Version: &models.Version{
+			Major: 1,
+			Stage: models.Version_Stable,
+		},
+	})
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for a version of a deleting dataset, got: %v", err)
+	}
+
+	objectHeritageHandler, err := NewObjectHeritageHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = objectHeritageHandler.CreateObjectHeritage(&services.CreateObjectHeritageRequest{
+		Name:      "late",
+		DatasetID: dataset.GetID(),
+	})
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for an object heritage of a deleting dataset, got: %v", err)
+	}
+
+	_, err = objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "missing",
+		DatasetID: "missingdataset",
+	}, dataset.GetProjectID())
+	if !apierrors.Is(err, apierrors.NotFound) {
+		t.Errorf("Expected not found error for an object group of a missing dataset, got: %v", err)
+	}
+}
+
+func TestDatasetActionHandler_UpdateDatasetFields(t *testing.T) {
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "updatetest",
+		Datatype:    "txt",
+		ProjectID:   uuid.New().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updatedEntry, err := datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+		"Datasetname": "renamed",
+		"IsPublic":    "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updatedEntry.GetDatasetname() != "renamed" {
+		t.Errorf("Datasetname was not updated")
+	}
+
+	if !updatedEntry.GetIsPublic() {
+		t.Errorf("IsPublic was not updated")
+	}
+
+	if updatedEntry.GetDatasettype() != entry.GetDatasettype() {
+		t.Errorf("Datasettype changed without being updated")
+	}
+
+	_, err = datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+		"ProjectID": "146",
+	})
+	if err == nil {
+		t.Errorf("Immutable field ProjectID was updated")
+	}
+
+	_, err = datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+		"IsPublic": "maybe",
+	})
+	if err == nil {
+		t.Errorf("Invalid boolean value was accepted")
+	}
+
+	updatedEntry, err = datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+		"Labels": `[{"Key": "sample", "Value": "XYZ"}, {"Key": "reviewed"}]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := updatedEntry.GetLabels()
+	if len(labels) != 2 || labels[0].GetKey() != "sample" || labels[0].GetValue() != "XYZ" || labels[1].GetKey() != "reviewed" {
+		t.Errorf("Labels were not updated: %v", labels)
+	}
+
+	invalidLabels := []string{"sample=XYZ", `[{"Value": "XYZ"}]`}
+	for _, value := range invalidLabels {
+		_, err = datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+			"Labels": value,
+		})
+		if !apierrors.Is(err, apierrors.InvalidArgument) {
+			t.Errorf("Expected invalid argument error for labels %v, got: %v", value, err)
+		}
+	}
+
+	_, err = datasetHandler.UpdateDatasetFields(entry.GetID(), map[string]string{
+		"AdditionalMetadata": "{}",
+	})
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for AdditionalMetadata, got: %v", err)
+	}
+}
+
+func TestDatasetActionHandler_CreateNewDatasetDuplicateName(t *testing.T) {
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	datasetRequest := services.CreateDatasetRequest{
+		DatasetName: "duplicatetest",
+		Datatype:    "txt",
+		ProjectID:   uuid.New().String(),
+	}
+
+	_, err = datasetHandler.CreateNewDataset(&datasetRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetHandler.CreateNewDataset(&datasetRequest)
+	if !apierrors.Is(err, apierrors.AlreadyExists) {
+		t.Errorf("Expected already exists error for duplicate dataset name, got: %v", err)
+	}
+
+	otherEntry, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "otherduplicatetest",
+		Datatype:    "txt",
+		ProjectID:   datasetRequest.GetProjectID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetHandler.UpdateDatasetFields(otherEntry.GetID(), map[string]string{
+		"Datasetname": datasetRequest.GetDatasetName(),
+	})
+	if !apierrors.Is(err, apierrors.AlreadyExists) {
+		t.Errorf("Expected already exists error for renaming to a duplicate dataset name, got: %v", err)
+	}
+
+	datasetRequest.ProjectID = uuid.New().String()
+
+	_, err = datasetHandler.CreateNewDataset(&datasetRequest)
+	if err != nil {
+		t.Errorf("Dataset name of another project was rejected: %v", err)
+	}
+}
+
+func TestDatasetVersionActionHandler_ReleaseDatasetVersionConcurrently(t *testing.T) {
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	datasetVersionHandler, err := NewDatasetVersionHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "concurrentreleasetest",
+		Datatype:    "txt",
+		ProjectID:   uuid.New().String(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const releases = 10
+
+	revisions := make(chan int32, releases)
+	errs := make(chan error, releases)
+
+	var wg sync.WaitGroup
+	for i := 0; i < releases; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			versionEntry, err := datasetVersionHandler.ReleaseDatasetVersion(&services.ReleaseDatasetVersionRequest{
+				Name:      "concurrent",
+				DatasetID: entry.GetID(),
+				Version: &models.Version{
+					Major: 1,
+					Stage: models.Version_Stable,
+				},
+				ObjectGroupIDs: make([]string, 0),
+			})
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			revisions <- versionEntry.GetVersion().GetRevision()
+		}()
+	}
+
+	wg.Wait()
+	close(revisions)
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	releasedRevisions := make(map[int32]bool)
+	for revision := range revisions {
+		if releasedRevisions[revision] {
+			t.Errorf("Revision %v was released more than once", revision)
+		}
+
+		releasedRevisions[revision] = true
+	}
+
+	for revision := int32(1); revision <= releases; revision++ {
+		if !releasedRevisions[revision] {
+			t.Errorf("Revision %v was not released", revision)
+		}
+	}
+
+	// Without its counter the next release would get revision 1 again, the unique index rejects it
+	_, err = dbHandler.GetDatasetVersionRevisionCollection().DeleteMany(dbHandler.MongoDefaultContext, bson.M{"DatasetID": entry.GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetVersionHandler.ReleaseDatasetVersion(&services.ReleaseDatasetVersionRequest{
+		Name:      "duplicate",
+		DatasetID: entry.GetID(),
+		Version: &models.Version{
+			Major: 1,
+			Stage: models.Version_Stable,
+		},
+		ObjectGroupIDs: make([]string, 0),
+	})
+	if !apierrors.Is(err, apierrors.AlreadyExists) {
+		t.Errorf("Expected already exists error for a released revision, got: %v", err)
+	}
+}
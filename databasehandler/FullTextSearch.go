@@ -0,0 +1,272 @@
+package databasehandler
+
+import (
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//DefaultSearchLimit Number of hits returned by a full-text search if no limit is requested
+const DefaultSearchLimit = 20
+
+//MaxSearchLimit Maximal number of hits returned by a full-text search
+const MaxSearchLimit = 100
+
+//datasetTextIndex The text index of the dataset collection, it is created by Migrate
+var datasetTextIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "Datasetname", Value: "text"}},
+	Options: options.Index().SetName("Datasetname_text"),
+}
+
+//objectGroupTextIndex The text index of the object group collection, it is created by Migrate
+var objectGroupTextIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "Objects.Filename", Value: "text"}, {Key: "MetadataText", Value: "text"}},
+	Options: options.Index().SetName("ObjectsFilename_MetadataText_text"),
+}
+
+//objectGroupDocument The database entry of an object group
+//In addition to the fields of the API model it stores the string values of the additional metadata of the group and
+//its objects, so they can be part of the text index, and the time of the last status change of the group
+type objectGroupDocument struct {
+	*models.DatasetObjectGroup `json:",inline"`
+	MetadataText               []string  `json:"MetadataText"`
+	StatusChanged              time.Time `json:"StatusChanged"`
+}
+
+//newObjectGroupDocument Creates the database entry of an object group
+func newObjectGroupDocument(objectGroup *models.DatasetObjectGroup) *objectGroupDocument {
+	metadataText := metadataStrings(objectGroup.GetAdditionalMetadata())
+	for _, object := range objectGroup.GetObjects() {
+		metadataText = append(metadataText, metadataStrings(object.GetAdditionalMetadata())...)
+	}
+
+	return &objectGroupDocument{
+		DatasetObjectGroup: objectGroup,
+		MetadataText:       metadataText,
+		StatusChanged:      time.Now(),
+	}
+}
+
+//metadataStrings Collects all string values of additional metadata
+func metadataStrings(metadata map[string]*structpb.Struct) []string {
+	var values []string
+	for _, metadataStruct := range metadata {
+		values = appendStructStrings(values, metadataStruct)
+	}
+
+	return values
+}
+
+func appendStructStrings(values []string, metadataStruct *structpb.Struct) []string {
+	for _, value := range metadataStruct.GetFields() {
+		values = appendValueStrings(values, value)
+	}
+
+	return values
+}
+
+func appendValueStrings(values []string, value *structpb.Value) []string {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		values = append(values, kind.StringValue)
+	case *structpb.Value_StructValue:
+		values = appendStructStrings(values, kind.StructValue)
+	case *structpb.Value_ListValue:
+		for _, listValue := range kind.ListValue.GetValues() {
+			values = appendValueStrings(values, listValue)
+		}
+	}
+
+	return values
+}
+
+//SearchHit A dataset or object group that matches a full-text search
+type SearchHit struct {
+	Resource  models.Resource
+	ID        string
+	Name      string
+	ProjectID string
+	DatasetID string
+	Score     float64
+}
+
+//textSearchResult The fields of datasets and object groups that are read for a search hit
+type textSearchResult struct {
+	ID          string  `json:"ID"`
+	Datasetname string  `json:"Datasetname"`
+	Name        string  `json:"Name"`
+	ProjectID   string  `json:"ProjectID"`
+	DatasetID   string  `json:"DatasetID"`
+	Score       float64 `json:"Score"`
+}
+
+//FullTextSearch Searches the names of the datasets and the filenames and metadata of the object groups of the given projects
+//Returns at most limit hits ordered by their text score, the scores of datasets and object groups are ranked together
+func (handler *ProjectActionHandler) FullTextSearch(projectIDs []string, text string, limit int64) ([]*SearchHit, error) {
+	if text == "" {
+		return nil, apierrors.New(apierrors.InvalidArgument, "The search text must not be empty")
+	}
+
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	if len(projectIDs) == 0 {
+		return nil, nil
+	}
+
+	datasetHits, err := handler.textSearch(handler.GetDatasetCollection(), bson.M{
+		"ProjectID": bson.M{"$in": projectIDs},
+	}, text, limit)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	datasetProjects, err := handler.datasetProjectIDs(projectIDs)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	datasetIDs := make([]string, 0, len(datasetProjects))
+	for datasetID := range datasetProjects {
+		datasetIDs = append(datasetIDs, datasetID)
+	}
+
+	objectGroupHits, err := handler.textSearch(handler.GetDatasetObjectGroupCollection(), bson.M{
+		"DatasetID": bson.M{"$in": datasetIDs},
+	}, text, limit)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	hits := make([]*SearchHit, 0, len(datasetHits)+len(objectGroupHits))
+
+	for _, dataset := range datasetHits {
+		hits = append(hits, &SearchHit{
+			Resource:  models.Resource_Dataset,
+			ID:        dataset.ID,
+			Name:      dataset.Datasetname,
+			ProjectID: dataset.ProjectID,
+			DatasetID: dataset.ID,
+			Score:     dataset.Score,
+		})
+	}
+
+	for _, objectGroup := range objectGroupHits {
+		hits = append(hits, &SearchHit{
+			Resource:  models.Resource_DatasetObjectGroupResource,
+			ID:        objectGroup.ID,
+			Name:      objectGroup.Name,
+			ProjectID: datasetProjects[objectGroup.DatasetID],
+			DatasetID: objectGroup.DatasetID,
+			Score:     objectGroup.Score,
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	if int64(len(hits)) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+//textSearch Returns the best matching entries of a collection for the search text
+func (handler *ProjectActionHandler) textSearch(collection *mongo.Collection, filter bson.M, text string, limit int64) ([]*textSearchResult, error) {
+	textFilter := bson.M{
+		"$text": bson.M{"$search": text},
+	}
+	for key, value := range filter {
+		textFilter[key] = value
+	}
+
+	score := bson.M{"$meta": "textScore"}
+
+	findOptions := options.Find().
+		SetProjection(bson.M{"Score": score}).
+		SetSort(bson.M{"Score": score}).
+		SetLimit(limit)
+
+	csr, err := collection.Find(handler.MongoDefaultContext, textFilter, findOptions)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	var results []*textSearchResult
+
+	err = csr.All(handler.MongoDefaultContext, &results)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return results, nil
+}
+
+//datasetProjectIDs Returns the IDs of all datasets of the given projects mapped to the ID of their project
+func (handler *ProjectActionHandler) datasetProjectIDs(projectIDs []string) (map[string]string, error) {
+	csr, err := handler.GetDatasetCollection().Find(handler.MongoDefaultContext, bson.M{
+		"ProjectID": bson.M{"$in": projectIDs},
+	}, options.Find().SetProjection(bson.M{"ID": 1, "ProjectID": 1}))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	var datasets []*textSearchResult
+
+	err = csr.All(handler.MongoDefaultContext, &datasets)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	datasetProjects := make(map[string]string, len(datasets))
+	for _, dataset := range datasets {
+		datasetProjects[dataset.ID] = dataset.ProjectID
+	}
+
+	return datasetProjects, nil
+}
+
+//GetUserProjectIDs Returns the IDs of all projects in which the user holds the required right
+func (handler *ProjectActionHandler) GetUserProjectIDs(userID string, requiredRight models.Right) ([]string, error) {
+	projectIDs, err := handler.GetProjectCollection().Distinct(handler.MongoDefaultContext, "ID", bson.M{
+		"Users": bson.M{"$elemMatch": bson.M{
+			"UserID": userID,
+			"Rights": requiredRight,
+		}},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(projectIDs))
+	for _, projectID := range projectIDs {
+		if id, ok := projectID.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,147 @@
+package databasehandler
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/spf13/viper"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProjectActionHandler_FullTextSearch(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := projectHandler.CreateProject("searchuser", &services.CreateProjectRequest{
+		Name: "searchproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherProject, err := projectHandler.CreateProject("searchuser", &services.CreateProjectRequest{
+		Name: "othersearchproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "zebrafish sequencing",
+		Datatype:    "fastq",
+		ProjectID:   project.GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "zebrafish imaging",
+		Datatype:    "tiff",
+		ProjectID:   otherProject.GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := structpb.NewStruct(map[string]interface{}{
+		"organism": map[string]interface{}{"strain": "tuebingen"},
+		"tags":     []interface{}{"larva", 5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:               "run1",
+		DatasetID:          dataset.GetID(),
+		AdditionalMetadata: map[string]*structpb.Struct{"sample": metadata},
+		Objects:            []*services.CreateObjectRequest{{Filename: "zebrafish.fastq", Filetype: "fastq", ContentLen: 1}},
+	}, project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := projectHandler.FullTextSearch([]string{project.GetID()}, "zebrafish", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foundResources := make(map[models.Resource]string)
+	for _, hit := range hits {
+		if hit.ProjectID != project.GetID() {
+			t.Errorf("Hit %v of project %v is outside of the searched project", hit.ID, hit.ProjectID)
+		}
+		foundResources[hit.Resource] = hit.ID
+	}
+
+	if foundResources[models.Resource_Dataset] != dataset.GetID() {
+		t.Errorf("Dataset was not found by its name")
+	}
+
+	if foundResources[models.Resource_DatasetObjectGroupResource] != objectGroup.GetID() {
+		t.Errorf("Object group was not found by its filename")
+	}
+
+	hits, err = projectHandler.FullTextSearch([]string{project.GetID()}, "tuebingen", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 1 || hits[0].ID != objectGroup.GetID() {
+		t.Errorf("Object group was not found by a nested metadata value, got %v hits", len(hits))
+	}
+}
+
+func TestProjectActionHandler_GetUserProjectIDs(t *testing.T) {
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	readableProject, err := projectHandler.CreateProject("projectidsowner", &services.CreateProjectRequest{
+		Name: "readableproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.AddUserToProject("projectidsuser", readableProject.GetID(), []models.Right{models.Right_Read})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeOnlyProject, err := projectHandler.CreateProject("projectidsowner", &services.CreateProjectRequest{
+		Name: "writeonlyproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.AddUserToProject("projectidsuser", writeOnlyProject.GetID(), []models.Right{models.Right_Write})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectIDs, err := projectHandler.GetUserProjectIDs("projectidsuser", models.Right_Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(projectIDs) != 1 || projectIDs[0] != readableProject.GetID() {
+		t.Errorf("Expected only project %v to be readable, got %v", readableProject.GetID(), projectIDs)
+	}
+}
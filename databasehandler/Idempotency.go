@@ -0,0 +1,134 @@
+package databasehandler
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//IdempotencyKeyCollectionName The name of the mongo collection that stores the idempotency keys of create requests
+const IdempotencyKeyCollectionName = "IdempotencyKeys"
+
+//IdempotencyKeyLifetime The duration after which an idempotency key expires and can be reused
+const IdempotencyKeyLifetime = 24 * time.Hour
+
+//idempotencyKeyEntry The database entry of an idempotency key
+//The RequestHash identifies the request the key was used for, a key can not be reused for a different request
+type idempotencyKeyEntry struct {
+	Resource    models.Resource `json:"Resource"`
+	Scope       string          `json:"Scope"`
+	Key         string          `json:"Key"`
+	RequestHash string          `json:"RequestHash"`
+	ResourceID  string          `json:"ResourceID"`
+	Created     time.Time       `json:"Created"`
+}
+
+//idempotencyKeyIndexes The indexes of the idempotency key collection, they are created by Migrate
+var idempotencyKeyIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "Resource", Value: 1}, {Key: "Scope", Value: 1}, {Key: "Key", Value: 1}},
+		Options: options.Index().SetName("Resource_Scope_Key").SetUnique(true),
+	},
+	{
+		Keys:    bson.D{{Key: "Created", Value: 1}},
+		Options: options.Index().SetName("Created").SetExpireAfterSeconds(int32(IdempotencyKeyLifetime.Seconds())),
+	},
+}
+
+//IdempotentCreate Creates a resource with the given handler and returns its ID
+//The handler runs the database operations in the transaction of the idempotency key
+type IdempotentCreate func(handler *DBUtilsHandler) (string, error)
+
+//GetIdempotencyKeyCollection Returns the collection that stores the idempotency keys of create requests
+func (handler *DBUtilsHandler) GetIdempotencyKeyCollection() *mongo.Collection {
+	return handler.GetManagementDatabase().Collection(IdempotencyKeyCollectionName)
+}
+
+//CreateIdempotent Runs create only once for a key within the scope, usually the requesting user, and the resource type
+//Returns the ID of the created resource and whether it was created by this call
+//A retried request with the same key and request hash returns the ID of the resource created by the first request,
+//a request with the same key but a different hash is rejected
+//The key is reserved and the resource created in one transaction, so a failed creation does not leave a used key behind
+func (handler *DBUtilsHandler) CreateIdempotent(resource models.Resource, scope string, key string, requestHash string, create IdempotentCreate) (string, bool, error) {
+	if key == "" {
+		return "", false, apierrors.New(apierrors.InvalidArgument, "The idempotency key must not be empty")
+	}
+
+	filter := bson.M{
+		"Resource": resource,
+		"Scope":    scope,
+		"Key":      key,
+	}
+
+	var id string
+	var keyUsed bool
+
+	err := handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+		_, err := transactionHandler.GetIdempotencyKeyCollection().InsertOne(transactionHandler.MongoDefaultContext, &idempotencyKeyEntry{
+			Resource:    resource,
+			Scope:       scope,
+			Key:         key,
+			RequestHash: requestHash,
+			Created:     time.Now(),
+		})
+		keyUsed = mongo.IsDuplicateKeyError(err)
+		if err != nil {
+			return err
+		}
+
+		id, err = create(transactionHandler)
+		if err != nil {
+			return err
+		}
+
+		_, err = transactionHandler.GetIdempotencyKeyCollection().UpdateOne(transactionHandler.MongoDefaultContext, filter, bson.M{
+			"$set": bson.M{"ResourceID": id},
+		})
+		return err
+	})
+
+	if keyUsed {
+		return handler.idempotentResourceID(filter, key, requestHash)
+	}
+
+	// A concurrent request with the same key has reserved it in a transaction that is not committed yet
+	if isTransientTransactionError(err) {
+		return "", false, apierrors.New(apierrors.FailedPrecondition, "The request with idempotency key %v is still in progress", key)
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		return "", false, err
+	}
+
+	return id, true, nil
+}
+
+//idempotentResourceID Returns the ID of the resource that was created for an already used idempotency key
+func (handler *DBUtilsHandler) idempotentResourceID(filter bson.M, key string, requestHash string) (string, bool, error) {
+	queryResult := handler.GetIdempotencyKeyCollection().FindOne(handler.MongoDefaultContext, filter)
+	if queryResult.Err() != nil {
+		log.Println(queryResult.Err().Error())
+		return "", false, queryResult.Err()
+	}
+
+	entry := idempotencyKeyEntry{}
+
+	err := queryResult.Decode(&entry)
+	if err != nil {
+		log.Println(err.Error())
+		return "", false, err
+	}
+
+	if entry.RequestHash != requestHash {
+		return "", false, apierrors.New(apierrors.InvalidArgument, "The idempotency key %v was already used for a different request", key)
+	}
+
+	return entry.ResourceID, false, nil
+}
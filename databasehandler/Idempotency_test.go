@@ -0,0 +1,93 @@
+package databasehandler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDBUtilsHandler_CreateIdempotent(t *testing.T) {
+	key := uuid.New().String()
+	creations := 0
+
+	create := func(handler *DBUtilsHandler) (string, error) {
+		creations++
+		return uuid.New().String(), nil
+	}
+
+	id, created, err := dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", key, "requesthash", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !created {
+		t.Errorf("First request with idempotency key %v did not create a resource", key)
+	}
+
+	retriedID, created, err := dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", key, "requesthash", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created || retriedID != id || creations != 1 {
+		t.Errorf("Retried request created a resource: created %v, id %v, expected id %v", created, retriedID, id)
+	}
+
+	_, _, err = dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", key, "otherrequesthash", create)
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for idempotency key reused for another request, got: %v", err)
+	}
+
+	_, created, err = dbHandler.CreateIdempotent(models.Resource_Dataset, "otheruser", key, "requesthash", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !created {
+		t.Errorf("Idempotency key of another user was reused")
+	}
+
+	failingKey := uuid.New().String()
+	failedID := uuid.New().String()
+
+	_, _, err = dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", failingKey, "requesthash", func(handler *DBUtilsHandler) (string, error) {
+		_, err := handler.GetDatasetCollection().InsertOne(handler.MongoDefaultContext, bson.M{"ID": failedID})
+		if err != nil {
+			return "", err
+		}
+
+		return "", apierrors.New(apierrors.InvalidArgument, "failed")
+	})
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected error of the failed creation, got: %v", err)
+	}
+
+	count, err := dbHandler.GetDatasetCollection().CountDocuments(dbHandler.MongoDefaultContext, bson.M{"ID": failedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Errorf("Writes of a failed creation were not rolled back")
+	}
+
+	_, created, err = dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", failingKey, "requesthash", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !created {
+		t.Errorf("Idempotency key of a failed request was not released")
+	}
+
+	_, _, err = dbHandler.CreateIdempotent(models.Resource_Dataset, "testuser", "", "requesthash", func(handler *DBUtilsHandler) (string, error) {
+		return "", errors.New("created without idempotency key")
+	})
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for empty idempotency key, got: %v", err)
+	}
+}
@@ -0,0 +1,434 @@
+package databasehandler
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//SchemaMigrationsCollectionName The name of the mongo collection that records the applied migrations
+const SchemaMigrationsCollectionName = "SchemaMigrations"
+
+//Migration An ordered change of the stored data
+//Migrations are applied once in the order of their versions, but have to be idempotent since an interrupted
+//or concurrent startup can apply a migration again before it is recorded
+type Migration struct {
+	Version     int64
+	Description string
+	Apply       func(handler *DBUtilsHandler) error
+}
+
+//migrations All migrations ordered by their version, new migrations are appended with the next version
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Replace plaintext API tokens by their salted hashes",
+		Apply:       migratePlaintextTokens,
+	},
+	{
+		Version:     2,
+		Description: "Store the metadata text of existing object groups for the full-text search",
+		Apply:       migrateObjectGroupMetadataText,
+	},
+	{
+		Version:     3,
+		Description: "Resolve datasets whose name is already used by another dataset of the same project",
+		Apply:       migrateDuplicateDatasetNames,
+	},
+	{
+		Version:     4,
+		Description: "Initialize the revision counters of the dataset versions",
+		Apply:       migrateDatasetVersionRevisions,
+	},
+}
+
+//appliedMigration The database entry of an applied migration
+type appliedMigration struct {
+	Version     int64                  `json:"Version"`
+	Description string                 `json:"Description"`
+	Applied     *timestamppb.Timestamp `json:"Applied"`
+}
+
+//collectionIndexes The indexes of a single collection
+type collectionIndexes struct {
+	Collection *mongo.Collection
+	Indexes    []mongo.IndexModel
+}
+
+//uniqueIDIndex The unique index on the ID field that every collection has
+var uniqueIDIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "ID", Value: 1}},
+	Options: options.Index().SetName("ID").SetUnique(true),
+}
+
+//schemaMigrationsIndex The unique index on the version of the applied migrations
+var schemaMigrationsIndex = mongo.IndexModel{
+	Keys:    bson.D{{Key: "Version", Value: 1}},
+	Options: options.Index().SetName("Version").SetUnique(true),
+}
+
+//GetSchemaMigrationsCollection Returns the collection that records the applied migrations
+func (handler *DBUtilsHandler) GetSchemaMigrationsCollection() *mongo.Collection {
+	return handler.GetManagementDatabase().Collection(SchemaMigrationsCollectionName)
+}
+
+//indexes The indexes of all collections
+//The secondary indexes on the filtered fields include the ID as last key to support the paginated queries that are ordered by ID
+func (handler *DBUtilsHandler) indexes() []collectionIndexes {
+	objectGroupIndexes := []mongo.IndexModel{
+		uniqueIDIndex,
+		{
+			Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "ID", Value: 1}},
+			Options: options.Index().SetName("DatasetID_ID"),
+		},
+		{
+			Keys:    bson.D{{Key: "Objects.ID", Value: 1}},
+			Options: options.Index().SetName("ObjectsID"),
+		},
+		{
+			// Used to find the object groups that still reference keys in the object storage
+			Keys:    bson.D{{Key: "Objects.Location.Key", Value: 1}},
+			Options: options.Index().SetName("ObjectsLocationKey"),
+		},
+		{
+			Keys:    bson.D{{Key: "ObjectHeritageID", Value: 1}},
+			Options: options.Index().SetName("ObjectHeritageID"),
+		},
+		{
+			Keys:    bson.D{{Key: "Status", Value: 1}, {Key: "Objects.Created.seconds", Value: 1}},
+			Options: options.Index().SetName("Status_ObjectsCreated"),
+		},
+		{
+			Keys:    bson.D{{Key: "Status", Value: 1}, {Key: "StatusChanged", Value: 1}},
+			Options: options.Index().SetName("Status_StatusChanged"),
+		},
+		{
+			Keys:    bson.D{{Key: "Objects.MultipartUploadID", Value: 1}},
+			Options: options.Index().SetName("ObjectsMultipartUploadID").SetSparse(true),
+		},
+		objectGroupTextIndex,
+	}
+	objectGroupIndexes = append(objectGroupIndexes, objectGroupSearchIndexes...)
+
+	return []collectionIndexes{
+		{
+			Collection: handler.GetDatasetCollection(),
+			Indexes: []mongo.IndexModel{
+				uniqueIDIndex,
+				{
+					Keys:    bson.D{{Key: "ProjectID", Value: 1}, {Key: "ID", Value: 1}},
+					Options: options.Index().SetName("ProjectID_ID"),
+				},
+				{
+					Keys:    bson.D{{Key: "ProjectID", Value: 1}, {Key: "Datasetname", Value: 1}},
+					Options: options.Index().SetName("ProjectID_Datasetname").SetUnique(true),
+				},
+				datasetTextIndex,
+			},
+		},
+		{
+			Collection: handler.GetDatasetVersionCollection(),
+			Indexes: []mongo.IndexModel{
+				uniqueIDIndex,
+				{
+					Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "ID", Value: 1}},
+					Options: options.Index().SetName("DatasetID_ID"),
+				},
+				datasetVersionIndex,
+			},
+		},
+		{
+			Collection: handler.GetDatasetVersionRevisionCollection(),
+			Indexes:    []mongo.IndexModel{datasetVersionRevisionIndex},
+		},
+		{
+			Collection: handler.GetDatasetObjectGroupCollection(),
+			Indexes:    objectGroupIndexes,
+		},
+		{
+			Collection: handler.GetObjectHeritageCollection(),
+			Indexes: []mongo.IndexModel{
+				uniqueIDIndex,
+				{
+					Keys:    bson.D{{Key: "DatasetID", Value: 1}},
+					Options: options.Index().SetName("DatasetID"),
+				},
+			},
+		},
+		{
+			Collection: handler.GetProjectCollection(),
+			Indexes: []mongo.IndexModel{
+				uniqueIDIndex,
+				{
+					Keys:    bson.D{{Key: "Users.UserID", Value: 1}, {Key: "ID", Value: 1}},
+					Options: options.Index().SetName("UsersUserID_ID"),
+				},
+			},
+		},
+		{
+			Collection: handler.GetTokenCollection(),
+			Indexes: []mongo.IndexModel{
+				uniqueIDIndex,
+				{
+					Keys:    bson.D{{Key: "TokenPrefix", Value: 1}},
+					Options: options.Index().SetName("TokenPrefix"),
+				},
+				{
+					Keys:    bson.D{{Key: "UserID.UserID", Value: 1}},
+					Options: options.Index().SetName("UserIDUserID"),
+				},
+				{
+					// Only tokens that were not migrated yet still have a plaintext token
+					Keys:    bson.D{{Key: "Token", Value: 1}},
+					Options: options.Index().SetName("Token").SetSparse(true),
+				},
+			},
+		},
+		{
+			Collection: handler.GetSchemaMigrationsCollection(),
+			Indexes:    []mongo.IndexModel{schemaMigrationsIndex},
+		},
+		{
+			Collection: handler.GetIdempotencyKeyCollection(),
+			Indexes:    idempotencyKeyIndexes,
+		},
+	}
+}
+
+//Migrate Applies all migrations that have not been applied yet and creates the indexes of all collections
+//The migrations run before the remaining indexes are created, so they can resolve entries that would violate a unique index
+//It is safe to call Migrate on every startup
+func (handler *DBUtilsHandler) Migrate() error {
+	_, err := handler.GetSchemaMigrationsCollection().Indexes().CreateOne(handler.MongoDefaultContext, schemaMigrationsIndex)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	err = handler.applyMigrations(migrations)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	err = handler.CreateIndexes()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//CreateIndexes Creates the indexes of all collections, existing indexes are left untouched
+func (handler *DBUtilsHandler) CreateIndexes() error {
+	for _, collection := range handler.indexes() {
+		_, err := collection.Collection.Indexes().CreateMany(handler.MongoDefaultContext, collection.Indexes)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+//SchemaVersion Returns the version of the last applied migration, 0 if no migration was applied
+func (handler *DBUtilsHandler) SchemaVersion() (int64, error) {
+	queryResult := handler.GetSchemaMigrationsCollection().FindOne(handler.MongoDefaultContext, bson.M{},
+		options.FindOne().SetSort(bson.D{{Key: "Version", Value: -1}}))
+
+	if queryResult.Err() == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+
+	if queryResult.Err() != nil {
+		log.Println(queryResult.Err().Error())
+		return 0, queryResult.Err()
+	}
+
+	migration := appliedMigration{}
+
+	err := queryResult.Decode(&migration)
+	if err != nil {
+		log.Println(err.Error())
+		return 0, err
+	}
+
+	return migration.Version, nil
+}
+
+//applyMigrations Applies the migrations with a version above the schema version in order and records each applied migration
+func (handler *DBUtilsHandler) applyMigrations(migrations []Migration) error {
+	schemaVersion, err := handler.SchemaVersion()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= schemaVersion {
+			continue
+		}
+
+		log.Infof("Applying migration %v: %v", migration.Version, migration.Description)
+
+		err := migration.Apply(handler)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		_, err = handler.GetSchemaMigrationsCollection().InsertOne(handler.MongoDefaultContext, &appliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     timestamppb.Now(),
+		})
+		// A concurrently starting server may have recorded the migration already
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migratePlaintextTokens(handler *DBUtilsHandler) error {
+	tokenHandler := TokenActionHandler{
+		DBUtilsHandler: handler,
+	}
+
+	migratedTokens, err := tokenHandler.MigratePlaintextTokens()
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	log.Infof("Migrated %v plaintext API tokens to hashed tokens", migratedTokens)
+
+	return nil
+}
+
+func migrateObjectGroupMetadataText(handler *DBUtilsHandler) error {
+	csr, err := handler.GetDatasetObjectGroupCollection().Find(handler.MongoDefaultContext, bson.M{
+		"MetadataText": bson.M{"$exists": false},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	defer csr.Close(handler.MongoDefaultContext)
+
+	for csr.Next(handler.MongoDefaultContext) {
+		objectGroup := models.DatasetObjectGroup{}
+
+		err := csr.Decode(&objectGroup)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		_, err = handler.GetDatasetObjectGroupCollection().UpdateOne(handler.MongoDefaultContext, bson.M{
+			"ID": objectGroup.GetID(),
+		}, bson.M{
+			"$set": bson.M{"MetadataText": newObjectGroupDocument(&objectGroup).MetadataText},
+		})
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return csr.Err()
+	}
+
+	return nil
+}
+
+//duplicateDatasetName The project and name that are shared by multiple datasets
+type duplicateDatasetName struct {
+	ProjectID   string `json:"ProjectID"`
+	Datasetname string `json:"Datasetname"`
+}
+
+//duplicateDatasetNames The datasets of a project that share a name
+type duplicateDatasetNames struct {
+	Name       duplicateDatasetName `json:"_id"`
+	DatasetIDs []string             `json:"DatasetIDs"`
+}
+
+//migrateDuplicateDatasetNames Fails if datasets of the same project share a name, so the startup is stopped before
+//the unique index on the names is created
+//If Config.Migrations.RenameDuplicateDatasets is set the oldest dataset of each duplicate group keeps its name
+//and the ID is appended to the names of the others instead
+func migrateDuplicateDatasetNames(handler *DBUtilsHandler) error {
+	csr, err := handler.GetDatasetCollection().Aggregate(handler.MongoDefaultContext, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        bson.M{"ProjectID": "$ProjectID", "Datasetname": "$Datasetname"},
+			"DatasetIDs": bson.M{"$push": "$ID"},
+			"Count":      bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"Count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	var duplicates []*duplicateDatasetNames
+
+	err = csr.All(handler.MongoDefaultContext, &duplicates)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if len(duplicates) != 0 && !viper.GetBool("Config.Migrations.RenameDuplicateDatasets") {
+		var conflicts []string
+		for _, duplicate := range duplicates {
+			conflicts = append(conflicts, fmt.Sprintf("project %v: %q (datasets %v)",
+				duplicate.Name.ProjectID, duplicate.Name.Datasetname, strings.Join(duplicate.DatasetIDs, ", ")))
+		}
+
+		err := apierrors.New(apierrors.FailedPrecondition, "Datasets of the same project share a name, rename them or set "+
+			"Config.Migrations.RenameDuplicateDatasets to append the ID to the names of all but the oldest dataset: %v", strings.Join(conflicts, "; "))
+		log.Println(err.Error())
+		return err
+	}
+
+	renamedDatasets := 0
+	for _, duplicate := range duplicates {
+		for _, datasetID := range duplicate.DatasetIDs[1:] {
+			_, err := handler.GetDatasetCollection().UpdateOne(handler.MongoDefaultContext, bson.M{
+				"ID": datasetID,
+			}, mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{
+					"Datasetname": bson.M{"$concat": bson.A{"$Datasetname", " (", "$ID", ")"}},
+				}}},
+			})
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			renamedDatasets++
+		}
+	}
+
+	log.Infof("Renamed %v datasets with duplicate names", renamedDatasets)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package databasehandler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+func TestDBUtilsHandler_Migrate(t *testing.T) {
+	err := dbHandler.Migrate()
+	if err != nil {
+		t.Fatalf("Repeated migration failed: %v", err)
+	}
+
+	version, err := dbHandler.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != migrations[len(migrations)-1].Version {
+		t.Errorf("Expected schema version %v, got %v", migrations[len(migrations)-1].Version, version)
+	}
+}
+
+func TestDBUtilsHandler_applyMigrations(t *testing.T) {
+	migrationHandler := *dbHandler
+	migrationHandler.DatasetDatabaseName = "MigrationTest" + uuid.New().String()
+	defer migrationHandler.GetManagementDatabase().Drop(migrationHandler.MongoDefaultContext)
+
+	err := migrationHandler.CreateIndexes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var applied []int64
+	testMigrations := []Migration{
+		{Version: 1, Apply: func(handler *DBUtilsHandler) error { applied = append(applied, 1); return nil }},
+		{Version: 2, Apply: func(handler *DBUtilsHandler) error { applied = append(applied, 2); return nil }},
+	}
+
+	err = migrationHandler.applyMigrations(testMigrations[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = migrationHandler.applyMigrations(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != 2 || applied[0] != 1 || applied[1] != 2 {
+		t.Errorf("Expected every migration to be applied once in order, applied: %v", applied)
+	}
+
+	version, err := migrationHandler.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != 2 {
+		t.Errorf("Expected schema version 2, got %v", version)
+	}
+}
+
+func Test_migrateDuplicateDatasetNames(t *testing.T) {
+	migrationHandler := *dbHandler
+	migrationHandler.DatasetDatabaseName = "MigrationTest" + uuid.New().String()
+	defer migrationHandler.GetManagementDatabase().Drop(migrationHandler.MongoDefaultContext)
+
+	projectID := uuid.New().String()
+	datasetIDs := []string{uuid.New().String(), uuid.New().String()}
+
+	for _, datasetID := range datasetIDs {
+		_, err := migrationHandler.GetDatasetCollection().InsertOne(migrationHandler.MongoDefaultContext, &models.DatasetEntry{
+			ID:          datasetID,
+			Datasetname: "duplicate",
+			ProjectID:   projectID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := migrateDuplicateDatasetNames(&migrationHandler)
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for duplicate dataset names, got: %v", err)
+	}
+
+	datasetHandler, err := NewDatasetHandler(&migrationHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unchangedDataset, err := datasetHandler.GetDataset(datasetIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if unchangedDataset.GetDatasetname() != "duplicate" {
+		t.Errorf("Dataset was renamed without Config.Migrations.RenameDuplicateDatasets, got %v", unchangedDataset.GetDatasetname())
+	}
+
+	viper.Set("Config.Migrations.RenameDuplicateDatasets", true)
+	defer viper.Set("Config.Migrations.RenameDuplicateDatasets", false)
+
+	err = migrateDuplicateDatasetNames(&migrationHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keptDataset, err := datasetHandler.GetDataset(datasetIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keptDataset.GetDatasetname() != "duplicate" {
+		t.Errorf("Expected the oldest dataset to keep its name, got %v", keptDataset.GetDatasetname())
+	}
+
+	renamedDataset, err := datasetHandler.GetDataset(datasetIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedName := fmt.Sprintf("duplicate (%v)", datasetIDs[1])
+	if renamedDataset.GetDatasetname() != expectedName {
+		t.Errorf("Expected renamed dataset %v, got %v", expectedName, renamedDataset.GetDatasetname())
+	}
+
+	err = migrationHandler.CreateIndexes()
+	if err != nil {
+		t.Errorf("Unique indexes could not be created after the migration: %v", err)
+	}
+}
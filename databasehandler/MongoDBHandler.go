@@ -21,7 +21,8 @@ import (
 
 //NewMongoClient Connects to a mongodb
 func NewMongoClient(ctx context.Context) (*mongo.Client, error) {
-	ctx, _ = context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
 	mongoDBURL := viper.GetString("Config.Database.Mongo.URL")
 	if mongoDBURL == "" {
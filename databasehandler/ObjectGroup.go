@@ -1,20 +1,46 @@
 package databasehandler
 
 import (
+	"context"
 	"fmt"
 	"path"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
 	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+//ObjectGroupStatusUploading Status of an object group whose objects are being uploaded
+//The API does not define a dedicated uploading status, Status_Updating is used instead
+const ObjectGroupStatusUploading = models.Status_Updating
+
+//objectGroupTransitions The allowed status transitions of object groups, indexed by the current status
+//The API does not define an error status, an object group whose upload could not be verified stays uploading
+//and the reason is recorded in its UploadError field, which GetObjectGroup returns in the UploadError header
+var objectGroupTransitions = map[models.Status][]models.Status{
+	models.Status_Initiating:   {ObjectGroupStatusUploading, models.Status_Deleting},
+	ObjectGroupStatusUploading: {ObjectGroupStatusUploading, models.Status_Available, models.Status_Deleting},
+	models.Status_Available:    {models.Status_Deleting},
+	models.Status_Deleting:     {},
+}
+
+//defaultInitiatingTimeout Time without status change after which an initiating object group is considered stale if none is configured
+const defaultInitiatingTimeout = 24 * time.Hour
+
+//uploadErrorObjectGroup The reason of a failed upload that is stored with an object group in addition to the fields of the API model
+type uploadErrorObjectGroup struct {
+	UploadError string `json:"UploadError"`
+}
+
 type SingleObject struct {
 	ID      string
 	Objects []*models.DatasetObjectEntry
@@ -23,7 +49,8 @@ type SingleObject struct {
 //ObjectGroupHandler Handles dataset object group actions
 type ObjectGroupHandler struct {
 	*DBUtilsHandler
-	BucketName string
+	BucketName        string
+	InitiatingTimeout time.Duration
 }
 
 func NewObjectGroupHandler(dbUtilsHandler *DBUtilsHandler) (*ObjectGroupHandler, error) {
@@ -32,15 +59,23 @@ func NewObjectGroupHandler(dbUtilsHandler *DBUtilsHandler) (*ObjectGroupHandler,
 		log.Fatalln("No valid bucketname found")
 	}
 
+	initiatingTimeout := viper.GetDuration("Config.ObjectGroups.InitiatingTimeout")
+	if initiatingTimeout == 0 {
+		initiatingTimeout = defaultInitiatingTimeout
+	}
+
 	handler := ObjectGroupHandler{
-		DBUtilsHandler: dbUtilsHandler,
-		BucketName:     bucket,
+		DBUtilsHandler:    dbUtilsHandler,
+		BucketName:        bucket,
+		InitiatingTimeout: initiatingTimeout,
 	}
 
 	return &handler, nil
 }
 
 //CreateDatasetObjectGroupObject Creates a new dataset object group
+//The object group starts in the initiating state, object groups without objects are available immediately
+//Fails if the dataset does not exist or is being deleted
 func (handler *ObjectGroupHandler) CreateDatasetObjectGroupObject(request *services.CreateObjectGroupRequest, projectID string) (*models.DatasetObjectGroup, error) {
 	uuidString := uuid.New().String()
 
@@ -51,8 +86,13 @@ func (handler *ObjectGroupHandler) CreateDatasetObjectGroupObject(request *servi
 		Labels:             request.Labels,
 		AdditionalMetadata: request.AdditionalMetadata,
 		DatasetID:          request.DatasetID,
+		ObjectHeritageID:   request.ObjectHeritageID,
 		UploadedObjects:    0,
-		Status:             models.Status_Available,
+		Status:             models.Status_Initiating,
+	}
+
+	if len(request.GetObjects()) == 0 {
+		objectGroup.Status = models.Status_Available
 	}
 
 	var objects []*models.DatasetObjectEntry
@@ -61,7 +101,7 @@ func (handler *ObjectGroupHandler) CreateDatasetObjectGroupObject(request *servi
 		objectuuidString := fmt.Sprintf("%v-%v", uuidString, i)
 		uploadID := uuid.New().String()
 
-		objectKey := path.Join(projectID, request.DatasetID, uuidString, fmt.Sprintf("%v", i), requestedObject.Filename)
+		objectKey := path.Join(ObjectKeyPrefix(projectID, request.DatasetID, uuidString), fmt.Sprintf("%v", i), requestedObject.Filename)
 
 		object := models.DatasetObjectEntry{
 			ID:                 objectuuidString,
@@ -87,7 +127,21 @@ func (handler *ObjectGroupHandler) CreateDatasetObjectGroupObject(request *servi
 
 	insertedValue := &models.DatasetObjectGroup{}
 
-	err := handler.DBUtilsHandler.Insert(handler.DBUtilsHandler.GetDatasetObjectGroupCollection(), &objectGroup, insertedValue)
+	err := handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+		err := transactionHandler.reserveDatasetWrite(objectGroup.GetDatasetID())
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		err = transactionHandler.Insert(transactionHandler.GetDatasetObjectGroupCollection(), newObjectGroupDocument(&objectGroup), insertedValue)
+		if err != nil {
+			log.Println(err.Error())
+			return alreadyExistsError(err, "Object group %v already exists", objectGroup.GetID())
+		}
+
+		return nil
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -96,21 +150,248 @@ func (handler *ObjectGroupHandler) CreateDatasetObjectGroupObject(request *servi
 	return insertedValue, nil
 }
 
-func (handler *ObjectGroupHandler) FinishUpload(objectGroupID string) error {
-	_, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().UpdateOne(handler.MongoDefaultContext,
+//ObjectKeyPrefix Returns the object storage key prefix for the given path elements
+//Object keys are structured as <projectID>/<datasetID>/<objectGroupID>/<index>/<filename>
+func ObjectKeyPrefix(elements ...string) string {
+	return path.Join(elements...) + "/"
+}
+
+//StartUpload Marks an object group as uploading and clears the error of a previously failed upload
+func (handler *ObjectGroupHandler) StartUpload(objectGroupID string) error {
+	return handler.transitionStatus(objectGroupID, ObjectGroupStatusUploading, bson.M{
+		"UploadError": "",
+	})
+}
+
+//FinishUpload Marks the upload of an object group as finished and records the number of uploaded objects
+func (handler *ObjectGroupHandler) FinishUpload(objectGroupID string, uploadedObjects int64) error {
+	return handler.transitionStatus(objectGroupID, models.Status_Available, bson.M{
+		"UploadedObjects": uploadedObjects,
+		"UploadError":     "",
+	})
+}
+
+//FailUpload Records why the upload of an object group failed and the number of correctly uploaded objects
+//The object group stays uploading, so the upload can be retried
+func (handler *ObjectGroupHandler) FailUpload(objectGroupID string, uploadedObjects int64, uploadError string) error {
+	return handler.transitionStatus(objectGroupID, ObjectGroupStatusUploading, bson.M{
+		"UploadedObjects": uploadedObjects,
+		"UploadError":     uploadError,
+	})
+}
+
+//GetUploadError Returns why the last upload of an object group failed, it is empty if no upload failed
+func (handler *ObjectGroupHandler) GetUploadError(objectGroupID string) (string, error) {
+	result := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().FindOne(
+		handler.MongoDefaultContext,
 		bson.M{"ID": objectGroupID},
-		bson.M{"$set": bson.M{
-			"Status": models.Status_Available,
-		}},
+		options.FindOne().SetProjection(bson.M{"UploadError": 1}),
+	)
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return "", notFoundError(result.Err(), models.Resource_DatasetObjectGroupResource.String(), objectGroupID)
+	}
+
+	objectGroup := uploadErrorObjectGroup{}
+
+	err := result.Decode(&objectGroup)
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	return objectGroup.UploadError, nil
+}
+
+//transitionStatus Atomically sets the status and the additional fields of an object group
+//Fails if the transition from the current status is not allowed by objectGroupTransitions
+func (handler *ObjectGroupHandler) transitionStatus(objectGroupID string, status models.Status, additionalFields bson.M) error {
+	var allowedCurrentStates []models.Status
+	for currentStatus, targetStates := range objectGroupTransitions {
+		for _, targetStatus := range targetStates {
+			if targetStatus == status {
+				allowedCurrentStates = append(allowedCurrentStates, currentStatus)
+			}
+		}
+	}
+
+	updatedFields := bson.M{"Status": status, "StatusChanged": time.Now()}
+	for key, value := range additionalFields {
+		updatedFields[key] = value
+	}
+
+	updateResult, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{
+			"ID":     objectGroupID,
+			"Status": bson.M{"$in": allowedCurrentStates},
+		},
+		bson.M{"$set": updatedFields},
 	)
 	if err != nil {
 		log.Println(err.Error())
 		return err
 	}
 
+	if updateResult.MatchedCount == 0 {
+		objectGroup, err := handler.GetObjectGroup(objectGroupID)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		err = apierrors.New(apierrors.FailedPrecondition, "Object group %v can not change its status from %v to %v", objectGroupID, objectGroup.GetStatus(), status)
+		log.Println(err.Error())
+		return err
+	}
+
 	return nil
 }
 
+//MarkDeleting Marks an object group as being deleted
+func (handler *ObjectGroupHandler) MarkDeleting(objectGroupID string) error {
+	return handler.transitionStatus(objectGroupID, models.Status_Deleting, bson.M{})
+}
+
+//staleObjectGroupFilter Matches object groups that are initiating without a status change for longer than the configured timeout
+//Object groups that were created before the status changes were recorded fall back to the creation time of their objects
+func (handler *ObjectGroupHandler) staleObjectGroupFilter() bson.M {
+	cutoff := time.Now().Add(-handler.InitiatingTimeout)
+
+	return bson.M{
+		"Status": models.Status_Initiating,
+		"$or": bson.A{
+			bson.M{"StatusChanged": bson.M{"$lt": cutoff}},
+			bson.M{"StatusChanged": bson.M{"$exists": false}, "Objects.Created.seconds": bson.M{"$lt": cutoff.Unix()}},
+			bson.M{"StatusChanged": bson.M{"$exists": false}, "Objects.0": bson.M{"$exists": false}},
+		},
+	}
+}
+
+//MarkStaleDeleting Marks an object group as being deleted if it is still stale
+//Fails if the object group changed its status since it was found to be stale or if it is part of a dataset version
+func (handler *ObjectGroupHandler) MarkStaleDeleting(objectGroupID string) error {
+	return handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+		versionCount, err := transactionHandler.GetDatasetVersionCollection().CountDocuments(transactionHandler.MongoDefaultContext, bson.M{
+			"ObjectIDs": objectGroupID,
+		})
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		if versionCount != 0 {
+			err := apierrors.New(apierrors.FailedPrecondition, "Object group %v is part of a dataset version", objectGroupID)
+			log.Println(err.Error())
+			return err
+		}
+
+		filter := handler.staleObjectGroupFilter()
+		filter["ID"] = objectGroupID
+
+		updateResult, err := transactionHandler.GetDatasetObjectGroupCollection().UpdateOne(transactionHandler.MongoDefaultContext,
+			filter,
+			bson.M{"$set": bson.M{"Status": models.Status_Deleting, "StatusChanged": time.Now()}},
+		)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		if updateResult.MatchedCount == 0 {
+			err := apierrors.New(apierrors.FailedPrecondition, "Object group %v is no longer stale", objectGroupID)
+			log.Println(err.Error())
+			return err
+		}
+
+		return nil
+	})
+}
+
+//DeleteObjectGroup Deletes an object group
+//Objects in the object storage have to be removed separately
+func (handler *ObjectGroupHandler) DeleteObjectGroup(objectGroupID string) error {
+	_, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().DeleteOne(handler.MongoDefaultContext, bson.M{
+		"ID": objectGroupID,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//GetReferencedObjectKeys Returns which of the given object storage keys are referenced by an object of an object group
+func (handler *ObjectGroupHandler) GetReferencedObjectKeys(keys []string) (map[string]bool, error) {
+	results, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().Distinct(handler.MongoDefaultContext, "Objects.Location.Key", bson.M{
+		"Objects.Location.Key": bson.M{"$in": keys},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	referencedKeys := make(map[string]bool)
+	for _, result := range results {
+		if key, ok := result.(string); ok {
+			referencedKeys[key] = true
+		}
+	}
+
+	return referencedKeys, nil
+}
+
+//GetStaleObjectGroups Returns all object groups that are initiating without a status change for longer than the configured timeout
+//Object groups that are part of a dataset version are never stale
+func (handler *ObjectGroupHandler) GetStaleObjectGroups() ([]*models.DatasetObjectGroup, error) {
+	var objectGroups []*models.DatasetObjectGroup
+
+	results, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().Find(handler.MongoDefaultContext, handler.staleObjectGroupFilter())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = results.All(handler.MongoDefaultContext, &objectGroups)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if len(objectGroups) == 0 {
+		return objectGroups, nil
+	}
+
+	var objectGroupIDs []string
+	for _, objectGroup := range objectGroups {
+		objectGroupIDs = append(objectGroupIDs, objectGroup.GetID())
+	}
+
+	versionedObjectGroupIDs, err := handler.DBUtilsHandler.GetDatasetVersionCollection().Distinct(handler.MongoDefaultContext, "ObjectIDs", bson.M{
+		"ObjectIDs": bson.M{"$in": objectGroupIDs},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	versionedObjectGroups := make(map[string]bool)
+	for _, result := range versionedObjectGroupIDs {
+		if objectGroupID, ok := result.(string); ok {
+			versionedObjectGroups[objectGroupID] = true
+		}
+	}
+
+	var staleObjectGroups []*models.DatasetObjectGroup
+	for _, objectGroup := range objectGroups {
+		if !versionedObjectGroups[objectGroup.GetID()] {
+			staleObjectGroups = append(staleObjectGroups, objectGroup)
+		}
+	}
+
+	return staleObjectGroups, nil
+}
+
 func (handler *ObjectGroupHandler) GetObjectGroup(objectGroupID string) (*models.DatasetObjectGroup, error) {
 	result := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().FindOne(handler.MongoDefaultContext, bson.M{
 		"ID": objectGroupID,
@@ -122,7 +403,7 @@ func (handler *ObjectGroupHandler) GetObjectGroup(objectGroupID string) (*models
 
 	if result.Err() != nil {
 		log.Println(result.Err().Error())
-		return nil, result.Err()
+		return nil, notFoundError(result.Err(), models.Resource_DatasetObjectGroupResource.String(), objectGroupID)
 	}
 
 	err := result.Decode(&datasetObjectGroup)
@@ -134,24 +415,24 @@ func (handler *ObjectGroupHandler) GetObjectGroup(objectGroupID string) (*models
 	return &datasetObjectGroup, nil
 }
 
-func (handler *ObjectGroupHandler) GetObjectGroups(objectGroupID []string) ([]*models.DatasetObjectGroup, error) {
+//GetObjectGroups Lists a page of the object groups with the given IDs and returns the token of the next page
+func (handler *ObjectGroupHandler) GetObjectGroups(objectGroupIDs []string, page *PageRequest) ([]*models.DatasetObjectGroup, string, error) {
 	var objectGroups []*models.DatasetObjectGroup
 
-	results, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().Find(handler.MongoDefaultContext, bson.M{
-		"ID": bson.M{"$in": objectGroupID},
+	nextPageToken, err := handler.findPage(handler.DBUtilsHandler.GetDatasetObjectGroupCollection(), bson.M{
+		"ID": bson.M{"$in": objectGroupIDs},
+	}, page, func(csr *mongo.Cursor) (string, error) {
+		objectGroup := models.DatasetObjectGroup{}
+		err := csr.Decode(&objectGroup)
+		objectGroups = append(objectGroups, &objectGroup)
+		return objectGroup.GetID(), err
 	})
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, "", err
 	}
 
-	err = results.All(handler.MongoDefaultContext, objectGroups)
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-
-	return objectGroups, nil
+	return objectGroups, nextPageToken, nil
 }
 
 func (handler *ObjectGroupHandler) GetObject(objectID string) (string, *models.DatasetObjectEntry, error) {
@@ -169,7 +450,7 @@ func (handler *ObjectGroupHandler) GetObject(objectID string) (string, *models.D
 
 	if result.Err() != nil {
 		log.Println(result.Err().Error())
-		return "", nil, result.Err()
+		return "", nil, notFoundError(result.Err(), models.Resource_DatasetObject.String(), objectID)
 	}
 
 	err := result.Decode(&datasetObject)
@@ -181,23 +462,212 @@ func (handler *ObjectGroupHandler) GetObject(objectID string) (string, *models.D
 	return datasetObject.ID, datasetObject.Objects[0], nil
 }
 
-//GetDatasetObjects Lists all objectgroups of a dataset
-func (handler *ObjectGroupHandler) GetDatasetObjects(datasetID string) ([]*models.DatasetObjectGroup, error) {
-	var objectGroups []*models.DatasetObjectGroup
+//multipartUploadObject The multipart upload state that is stored with an object in addition to the fields of the API model
+//MultipartUploadID is only set while a multipart upload of the object is in progress
+type multipartUploadObject struct {
+	ID                string `json:"ID"`
+	MultipartUploadID string `json:"MultipartUploadID"`
+}
 
-	results, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().Find(handler.MongoDefaultContext, bson.M{
-		"DatasetID": datasetID,
+//multipartUploadObjectGroup The multipart upload state of the objects of an object group
+type multipartUploadObjectGroup struct {
+	Objects []*multipartUploadObject `json:"Objects"`
+}
+
+//StartMultipartUpload Records the upload id of a multipart upload that was initiated for an object
+func (handler *ObjectGroupHandler) StartMultipartUpload(objectID string, uploadID string) error {
+	return handler.updateObject(objectID, bson.M{
+		"$set": bson.M{"Objects.$.UploadID": uploadID, "Objects.$.MultipartUploadID": uploadID},
 	})
+}
+
+//GetMultipartUploadID Returns the upload id of the multipart upload of an object
+//Fails if no multipart upload was initiated for the object or if it was already completed or aborted
+func (handler *ObjectGroupHandler) GetMultipartUploadID(objectID string) (string, error) {
+	result := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().FindOne(
+		handler.MongoDefaultContext,
+		bson.M{"Objects.ID": objectID},
+		options.FindOne().SetProjection(bson.M{"Objects.$": 1}),
+	)
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return "", notFoundError(result.Err(), models.Resource_DatasetObject.String(), objectID)
+	}
+
+	objectGroup := multipartUploadObjectGroup{}
+
+	err := result.Decode(&objectGroup)
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return "", err
 	}
 
-	err = results.All(handler.MongoDefaultContext, &objectGroups)
+	if len(objectGroup.Objects) == 0 || objectGroup.Objects[0].MultipartUploadID == "" {
+		return "", apierrors.New(apierrors.FailedPrecondition, "No multipart upload was initiated for object %v", objectID)
+	}
+
+	return objectGroup.Objects[0].MultipartUploadID, nil
+}
+
+//FinishMultipartUpload Records that the multipart upload of an object was completed
+func (handler *ObjectGroupHandler) FinishMultipartUpload(objectID string) error {
+	return handler.updateObject(objectID, bson.M{
+		"$unset": bson.M{"Objects.$.MultipartUploadID": ""},
+	})
+}
+
+//ClearMultipartUpload Removes the upload id of an aborted multipart upload from an object
+func (handler *ObjectGroupHandler) ClearMultipartUpload(objectID string) error {
+	return handler.updateObject(objectID, bson.M{
+		"$set":   bson.M{"Objects.$.UploadID": ""},
+		"$unset": bson.M{"Objects.$.MultipartUploadID": ""},
+	})
+}
+
+//ClearAbandonedMultipartUpload Removes the upload id of an aborted multipart upload from the object it was initiated for
+//Multipart uploads that do not belong to an object are ignored
+func (handler *ObjectGroupHandler) ClearAbandonedMultipartUpload(uploadID string) error {
+	_, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{"Objects.MultipartUploadID": uploadID},
+		bson.M{
+			"$set":   bson.M{"Objects.$.UploadID": ""},
+			"$unset": bson.M{"Objects.$.MultipartUploadID": ""},
+		},
+	)
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return err
+	}
+
+	return nil
+}
+
+//updateObject Applies an update to the object with the given id, fields of the object are addressed with Objects.$
+func (handler *ObjectGroupHandler) updateObject(objectID string, update bson.M) error {
+	updateResult, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{"Objects.ID": objectID},
+		update,
+	)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if updateResult.MatchedCount == 0 {
+		return apierrors.NewNotFound(models.Resource_DatasetObject.String(), objectID)
+	}
+
+	return nil
+}
+
+//ObjectGroupSender Sends a single object group, e.g. as a message of a gRPC stream
+type ObjectGroupSender func(objectGroup *models.DatasetObjectGroup) error
+
+//StreamDatasetObjects Passes all object groups of a dataset to send while they are read from the database
+func (handler *ObjectGroupHandler) StreamDatasetObjects(ctx context.Context, datasetID string, send ObjectGroupSender) error {
+	return handler.streamObjectGroups(ctx, bson.M{
+		"DatasetID": datasetID,
+	}, send)
+}
+
+//StreamObjectGroups Passes the object groups with the given IDs to send while they are read from the database
+func (handler *ObjectGroupHandler) StreamObjectGroups(ctx context.Context, objectGroupIDs []string, send ObjectGroupSender) error {
+	return handler.streamObjectGroups(ctx, bson.M{
+		"ID": bson.M{"$in": objectGroupIDs},
+	}, send)
+}
+
+//streamObjectGroups Iterates the object groups matching the filter ordered by their ID
+//The next object group is only decoded after send returned, so a blocking send slows down the iteration
+//If the database discards the cursor of a slow consumer, the iteration is resumed after the last sent object group
+//The iteration stops as soon as the context is canceled
+func (handler *ObjectGroupHandler) streamObjectGroups(ctx context.Context, filter bson.M, send ObjectGroupSender) error {
+	lastID := ""
+
+	for {
+		resumeFilter := filter
+		if lastID != "" {
+			resumeFilter = bson.M{"$and": bson.A{
+				filter,
+				bson.M{"ID": bson.M{"$gt": lastID}},
+			}}
+		}
+
+		previousLastID := lastID
+
+		err := handler.streamObjectGroupsAfter(ctx, resumeFilter, &lastID, send)
+		if isCursorNotFoundError(err) && lastID != previousLastID {
+			log.Infof("Resuming stream of object groups after %v: %v", lastID, err.Error())
+			continue
+		}
+
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		return nil
+	}
+}
+
+//streamObjectGroupsAfter Passes the object groups matching the filter to send using a single cursor
+//The ID of every sent object group is stored in lastID, so the iteration can be resumed if the cursor is lost
+func (handler *ObjectGroupHandler) streamObjectGroupsAfter(ctx context.Context, filter bson.M, lastID *string, send ObjectGroupSender) error {
+	findOptions := options.Find().SetSort(bson.D{{Key: "ID", Value: 1}})
+
+	csr, err := handler.DBUtilsHandler.GetDatasetObjectGroupCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+	defer csr.Close(handler.MongoDefaultContext)
+
+	for csr.Next(ctx) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		objectGroup := models.DatasetObjectGroup{}
+
+		err := csr.Decode(&objectGroup)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		err = send(&objectGroup)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		*lastID = objectGroup.GetID()
+	}
+
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return csr.Err()
+	}
+
+	return nil
+}
+
+//GetDatasetObjects Lists a page of the objectgroups of a dataset and returns the token of the next page
+func (handler *ObjectGroupHandler) GetDatasetObjects(datasetID string, page *PageRequest) ([]*models.DatasetObjectGroup, string, error) {
+	var objectGroups []*models.DatasetObjectGroup
+
+	nextPageToken, err := handler.findPage(handler.DBUtilsHandler.GetDatasetObjectGroupCollection(), bson.M{
+		"DatasetID": datasetID,
+	}, page, func(csr *mongo.Cursor) (string, error) {
+		objectGroup := models.DatasetObjectGroup{}
+		err := csr.Decode(&objectGroup)
+		objectGroups = append(objectGroups, &objectGroup)
+		return objectGroup.GetID(), err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
 	}
 
-	return objectGroups, nil
+	return objectGroups, nextPageToken, nil
 }
@@ -0,0 +1,167 @@
+package databasehandler
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//ObjectGroupSearchQuery Filters the object groups of a single dataset or of all datasets of a project
+//All given conditions have to match, the object conditions have to match for the same object of a group
+type ObjectGroupSearchQuery struct {
+	ProjectID string
+	DatasetID string
+	//Labels Labels that are all attached to the object group, labels with an empty value only match the key
+	Labels []*models.Label
+	//Filename Exact filename of an object of the group
+	Filename string
+	//Filetype Exact filetype of an object of the group
+	Filetype string
+	//CreatedAfter Inclusive lower bound of the creation time of an object of the group
+	CreatedAfter *time.Time
+	//CreatedBefore Exclusive upper bound of the creation time of an object of the group
+	CreatedBefore *time.Time
+}
+
+//objectGroupSearchIndexes The indexes that back the conditions of object group searches, they are created by Migrate
+var objectGroupSearchIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "Labels.Key", Value: 1}, {Key: "Labels.Value", Value: 1}},
+		Options: options.Index().SetName("DatasetID_Labels"),
+	},
+	{
+		Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "Objects.Filename", Value: 1}},
+		Options: options.Index().SetName("DatasetID_ObjectsFilename"),
+	},
+	{
+		Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "Objects.Filetype", Value: 1}},
+		Options: options.Index().SetName("DatasetID_ObjectsFiletype"),
+	},
+	{
+		Keys:    bson.D{{Key: "DatasetID", Value: 1}, {Key: "Objects.Created.seconds", Value: 1}},
+		Options: options.Index().SetName("DatasetID_ObjectsCreated"),
+	},
+}
+
+//SearchObjectGroups Returns a page of the object groups that match the query and the token of the next page
+func (handler *ObjectGroupHandler) SearchObjectGroups(query *ObjectGroupSearchQuery, page *PageRequest) ([]*models.DatasetObjectGroup, string, error) {
+	filter, err := handler.searchFilter(query)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	var objectGroups []*models.DatasetObjectGroup
+
+	nextPageToken, err := handler.findPage(handler.GetDatasetObjectGroupCollection(), filter, page, func(csr *mongo.Cursor) (string, error) {
+		objectGroup := models.DatasetObjectGroup{}
+		err := csr.Decode(&objectGroup)
+		objectGroups = append(objectGroups, &objectGroup)
+		return objectGroup.GetID(), err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
+	}
+
+	return objectGroups, nextPageToken, nil
+}
+
+//searchFilter Translates a search query into a filter on the object group collection
+func (handler *ObjectGroupHandler) searchFilter(query *ObjectGroupSearchQuery) (bson.M, error) {
+	if (query.ProjectID == "") == (query.DatasetID == "") {
+		return nil, apierrors.New(apierrors.InvalidArgument, "Exactly one of ProjectID and DatasetID has to be set")
+	}
+
+	filter := bson.M{}
+
+	if query.DatasetID != "" {
+		filter["DatasetID"] = query.DatasetID
+	} else {
+		datasetIDs, err := handler.projectDatasetIDs(query.ProjectID)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		filter["DatasetID"] = bson.M{"$in": datasetIDs}
+	}
+
+	if len(query.Labels) != 0 {
+		var labelConditions bson.A
+		for _, label := range query.Labels {
+			if label.GetKey() == "" {
+				return nil, apierrors.New(apierrors.InvalidArgument, "Label keys must not be empty")
+			}
+
+			labelCondition := bson.M{"Key": label.GetKey()}
+			if label.GetValue() != "" {
+				labelCondition["Value"] = label.GetValue()
+			}
+
+			labelConditions = append(labelConditions, bson.M{"$elemMatch": labelCondition})
+		}
+
+		filter["Labels"] = bson.M{"$all": labelConditions}
+	}
+
+	objectCondition := bson.M{}
+
+	if query.Filename != "" {
+		objectCondition["Filename"] = query.Filename
+	}
+
+	if query.Filetype != "" {
+		objectCondition["Filetype"] = query.Filetype
+	}
+
+	createdCondition := bson.M{}
+
+	if query.CreatedAfter != nil {
+		createdCondition["$gte"] = query.CreatedAfter.Unix()
+	}
+
+	if query.CreatedBefore != nil {
+		createdCondition["$lt"] = query.CreatedBefore.Unix()
+	}
+
+	if query.CreatedAfter != nil && query.CreatedBefore != nil && !query.CreatedAfter.Before(*query.CreatedBefore) {
+		return nil, apierrors.New(apierrors.InvalidArgument, "CreatedAfter has to be before CreatedBefore")
+	}
+
+	if len(createdCondition) != 0 {
+		objectCondition["Created.seconds"] = createdCondition
+	}
+
+	if len(objectCondition) != 0 {
+		filter["Objects"] = bson.M{"$elemMatch": objectCondition}
+	}
+
+	return filter, nil
+}
+
+//projectDatasetIDs Returns the IDs of all datasets of a project
+func (handler *ObjectGroupHandler) projectDatasetIDs(projectID string) ([]string, error) {
+	datasetIDs, err := handler.GetDatasetCollection().Distinct(handler.MongoDefaultContext, "ID", bson.M{
+		"ProjectID": projectID,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(datasetIDs))
+	for _, datasetID := range datasetIDs {
+		if id, ok := datasetID.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
@@ -1,9 +1,12 @@
 package databasehandler
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
 	"github.com/spf13/viper"
@@ -14,13 +17,16 @@ func TestObjectGroupHandler_CreateDatasetObjectGroupObject(t *testing.T) {
 
 	viper.Set("Config.S3.Bucketname", "testbucket")
 
+	testDataset := createTestDataset(t, "createdataset")
+
 	datasetHandler, err := NewObjectGroupHandler(dbHandler)
 	if err != nil {
 		t.Error(err)
 	}
 
 	datasetRequest := services.CreateObjectGroupRequest{
-		Name: "foo",
+		Name:      "foo",
+		DatasetID: testDataset.GetID(),
 		Labels: []*models.Label{
 			{
 				Key:   "key1",
@@ -51,17 +57,37 @@ func TestObjectGroupHandler_CreateDatasetObjectGroupObject(t *testing.T) {
 		t.Errorf("Inserted dataset id does not match")
 	}
 
-	err = datasetHandler.FinishUpload(entry.GetID())
+	if entry.Status != models.Status_Initiating {
+		t.Errorf("New object group is not in the initiating state")
+	}
+
+	err = datasetHandler.FinishUpload(entry.GetID(), 1)
+	if err == nil {
+		t.Errorf("Object group upload was finished before it was started")
+	}
+
+	err = datasetHandler.StartUpload(entry.GetID())
 	if err != nil {
 		t.Error(err)
 	}
 
+	err = datasetHandler.FinishUpload(entry.GetID(), 1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = datasetHandler.StartUpload(entry.GetID())
+	if err == nil {
+		t.Errorf("Upload of an available object group was started again")
+	}
+
 	objectGroup, err := datasetHandler.GetObjectGroup(entry.GetID())
 	if err != nil {
 		t.Error(err)
 	}
 
 	entry.Status = models.Status_Available
+	entry.UploadedObjects = 1
 
 	isEqual := proto.Equal(objectGroup, entry)
 	if !isEqual {
@@ -77,3 +103,440 @@ func TestObjectGroupHandler_CreateDatasetObjectGroupObject(t *testing.T) {
 	}
 
 }
+
+func TestObjectGroupHandler_GetStaleObjectGroups(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "staledataset")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "stale",
+		DatasetID: testDataset.GetID(),
+		Objects: []*services.CreateObjectRequest{
+			{
+				Filename:   "stalefile",
+				Filetype:   "txt",
+				ContentLen: 9,
+			},
+		},
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadingEntry, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "uploading",
+		DatasetID: testDataset.GetID(),
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = objectGroupHandler.StartUpload(uploadingEntry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler.InitiatingTimeout = -time.Hour
+
+	staleGroups, err := objectGroupHandler.GetStaleObjectGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staleIDs := make(map[string]bool)
+	for _, group := range staleGroups {
+		staleIDs[group.GetID()] = true
+	}
+
+	if !staleIDs[entry.GetID()] {
+		t.Errorf("Object group %v was not reported as stale", entry.GetID())
+	}
+
+	if staleIDs[uploadingEntry.GetID()] {
+		t.Errorf("Uploading object group %v was reported as stale", uploadingEntry.GetID())
+	}
+
+	key := entry.GetObjects()[0].GetLocation().GetKey()
+
+	referencedKeys, err := objectGroupHandler.GetReferencedObjectKeys([]string{key, "orphaned/key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !referencedKeys[key] || referencedKeys["orphaned/key"] {
+		t.Errorf("Unexpected referenced keys: %v", referencedKeys)
+	}
+
+	versionedEntry, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "versioned",
+		DatasetID: testDataset.GetID(),
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	datasetVersionHandler, err := NewDatasetVersionHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = datasetVersionHandler.ReleaseDatasetVersion(&services.ReleaseDatasetVersionRequest{
+		Name:      "stale",
+		DatasetID: testDataset.GetID(),
+		Version: &models.Version{
+			Major: 1,
+			Stage: models.Version_Stable,
+		},
+		ObjectGroupIDs: []string{versionedEntry.GetID()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staleGroups, err = objectGroupHandler.GetStaleObjectGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, group := range staleGroups {
+		if group.GetID() == versionedEntry.GetID() {
+			t.Errorf("Object group %v of a dataset version was reported as stale", versionedEntry.GetID())
+		}
+	}
+
+	err = objectGroupHandler.MarkStaleDeleting(versionedEntry.GetID())
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for an object group of a dataset version, got: %v", err)
+	}
+
+	err = objectGroupHandler.MarkStaleDeleting(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = objectGroupHandler.MarkStaleDeleting(entry.GetID())
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error for an object group that is already deleting, got: %v", err)
+	}
+
+	err = objectGroupHandler.DeleteObjectGroup(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = objectGroupHandler.GetObjectGroup(entry.GetID())
+	if !apierrors.Is(err, apierrors.NotFound) {
+		t.Errorf("Expected not found error for deleted object group %v, got: %v", entry.GetID(), err)
+	}
+}
+
+func TestObjectGroupHandler_StreamDatasetObjects(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "streamdataset")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdObjectGroups := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+			Name:      fmt.Sprintf("streamed%v", i),
+			DatasetID: testDataset.GetID(),
+		}, "testproject")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		createdObjectGroups[objectGroup.GetID()] = true
+	}
+
+	streamedObjectGroups := make(map[string]bool)
+	err = objectGroupHandler.StreamDatasetObjects(context.Background(), testDataset.GetID(), func(objectGroup *models.DatasetObjectGroup) error {
+		streamedObjectGroups[objectGroup.GetID()] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streamedObjectGroups) != len(createdObjectGroups) {
+		t.Errorf("Expected %v streamed object groups, got %v", len(createdObjectGroups), len(streamedObjectGroups))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sent := 0
+	err = objectGroupHandler.StreamDatasetObjects(ctx, testDataset.GetID(), func(objectGroup *models.DatasetObjectGroup) error {
+		sent++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected canceled stream to fail with context.Canceled, got: %v", err)
+	}
+
+	if sent != 1 {
+		t.Errorf("Expected the stream to stop after cancellation, sent %v object groups", sent)
+	}
+}
+
+func TestObjectGroupHandler_GetObjectGroups(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "listdataset")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objectGroupIDs []string
+	requestedIDs := make(map[string]bool)
+	for i := 0; i < 6; i++ {
+		objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+			Name:      fmt.Sprintf("listed%v", i),
+			DatasetID: testDataset.GetID(),
+		}, "testproject")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Only every second object group is requested, the others must not show up in any page
+		if i%2 == 0 {
+			objectGroupIDs = append(objectGroupIDs, objectGroup.GetID())
+			requestedIDs[objectGroup.GetID()] = true
+		}
+	}
+
+	objectGroups, nextPageToken, err := objectGroupHandler.GetObjectGroups(objectGroupIDs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objectGroups) != len(objectGroupIDs) || nextPageToken != "" {
+		t.Errorf("Expected all %v object groups without page request, got %v and next page token %q", len(objectGroupIDs), len(objectGroups), nextPageToken)
+	}
+
+	objectGroups, nextPageToken, err = objectGroupHandler.GetObjectGroups(objectGroupIDs, &PageRequest{PageSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objectGroups) != 2 || nextPageToken == "" {
+		t.Fatalf("Expected a first page of 2 object groups, got %v and next page token %q", len(objectGroups), nextPageToken)
+	}
+
+	objectGroups, nextPageToken, err = objectGroupHandler.GetObjectGroups(objectGroupIDs, &PageRequest{PageSize: 2, PageToken: nextPageToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objectGroups) != 1 || nextPageToken != "" {
+		t.Errorf("Expected a last page of 1 object group, got %v and next page token %q", len(objectGroups), nextPageToken)
+	}
+
+	for _, objectGroup := range objectGroups {
+		if !requestedIDs[objectGroup.GetID()] {
+			t.Errorf("Object group %v was not requested but returned in the last page", objectGroup.GetID())
+		}
+	}
+}
+
+func TestObjectGroupHandler_SearchObjectGroups(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "searchdataset")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matching, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "matching",
+		DatasetID: testDataset.GetID(),
+		Labels:    []*models.Label{{Key: "sample", Value: "XYZ"}},
+		Objects:   []*services.CreateObjectRequest{{Filename: "reads.fastq", Filetype: "fastq", ContentLen: 1}},
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "other",
+		DatasetID: testDataset.GetID(),
+		Labels:    []*models.Label{{Key: "sample", Value: "ABC"}},
+		Objects:   []*services.CreateObjectRequest{{Filename: "reads.bam", Filetype: "bam", ContentLen: 1}},
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdAfter := time.Now().Add(-time.Hour)
+
+	queries := map[string]*ObjectGroupSearchQuery{
+		"label":         {DatasetID: testDataset.GetID(), Labels: []*models.Label{{Key: "sample", Value: "XYZ"}}},
+		"filetype":      {DatasetID: testDataset.GetID(), Filetype: "fastq"},
+		"filename":      {DatasetID: testDataset.GetID(), Filename: "reads.fastq", CreatedAfter: &createdAfter},
+		"label and key": {DatasetID: testDataset.GetID(), Labels: []*models.Label{{Key: "sample"}}, Filetype: "fastq"},
+	}
+
+	for name, query := range queries {
+		objectGroups, _, err := objectGroupHandler.SearchObjectGroups(query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(objectGroups) != 1 || objectGroups[0].GetID() != matching.GetID() {
+			t.Errorf("Search by %v returned %v object groups instead of the matching one", name, len(objectGroups))
+		}
+	}
+
+	createdBefore := time.Now().Add(-time.Hour)
+
+	objectGroups, _, err := objectGroupHandler.SearchObjectGroups(&ObjectGroupSearchQuery{DatasetID: testDataset.GetID(), CreatedBefore: &createdBefore}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objectGroups) != 0 {
+		t.Errorf("Search by creation time returned %v object groups created later", len(objectGroups))
+	}
+}
+
+func TestObjectGroupHandler_MultipartUpload(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "multipartdataset")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "multipart",
+		DatasetID: testDataset.GetID(),
+		Objects: []*services.CreateObjectRequest{
+			{
+				Filename:   "multipartfile",
+				Filetype:   "txt",
+				ContentLen: 9,
+			},
+		},
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectID := entry.GetObjects()[0].GetID()
+
+	_, err = objectGroupHandler.GetMultipartUploadID(objectID)
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error before the multipart upload was initiated, got: %v", err)
+	}
+
+	err = objectGroupHandler.StartMultipartUpload(objectID, "multipartuploadid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadID, err := objectGroupHandler.GetMultipartUploadID(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uploadID != "multipartuploadid" {
+		t.Errorf("Expected upload id multipartuploadid, got %v", uploadID)
+	}
+
+	err = objectGroupHandler.ClearMultipartUpload(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = objectGroupHandler.GetMultipartUploadID(objectID)
+	if !apierrors.Is(err, apierrors.FailedPrecondition) {
+		t.Errorf("Expected failed precondition error after the multipart upload was aborted, got: %v", err)
+	}
+
+	_, object, err := objectGroupHandler.GetObject(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if object.GetUploadID() != "" {
+		t.Errorf("Upload id %v of the aborted multipart upload was not removed", object.GetUploadID())
+	}
+}
+
+func TestObjectGroupHandler_FailUpload(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "failedupload")
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "failedupload",
+		DatasetID: testDataset.GetID(),
+		Objects: []*services.CreateObjectRequest{
+			{
+				Filename:   "failedfile",
+				Filetype:   "txt",
+				ContentLen: 9,
+			},
+		},
+	}, "testproject")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = objectGroupHandler.FailUpload(entry.GetID(), 0, "missing object")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroup, err := objectGroupHandler.GetObjectGroup(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if objectGroup.GetStatus() != ObjectGroupStatusUploading {
+		t.Errorf("Expected failed object group to stay uploading, got status %v", objectGroup.GetStatus())
+	}
+
+	uploadError, err := objectGroupHandler.GetUploadError(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uploadError != "missing object" {
+		t.Errorf("Expected upload error missing object, got %v", uploadError)
+	}
+
+	err = objectGroupHandler.FinishUpload(entry.GetID(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadError, err = objectGroupHandler.GetUploadError(entry.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if uploadError != "" {
+		t.Errorf("Upload error %v was not cleared by a finished upload", uploadError)
+	}
+}
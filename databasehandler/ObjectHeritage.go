@@ -0,0 +1,140 @@
+package databasehandler
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//ObjectHeritageHandler Handles object heritage actions
+//An object heritage groups object groups of a dataset that are revisions of the same logical object
+type ObjectHeritageHandler struct {
+	*DBUtilsHandler
+}
+
+//NewObjectHeritageHandler Initializes a new object heritage handler
+func NewObjectHeritageHandler(dbUtilsHandler *DBUtilsHandler) (*ObjectHeritageHandler, error) {
+	handler := ObjectHeritageHandler{
+		DBUtilsHandler: dbUtilsHandler,
+	}
+
+	return &handler, nil
+}
+
+//CreateObjectHeritage Creates a new object heritage
+//Fails if the dataset does not exist or is being deleted
+func (handler *ObjectHeritageHandler) CreateObjectHeritage(request *services.CreateObjectHeritageRequest) (*models.ObjectHeritage, error) {
+	objectHeritage := models.ObjectHeritage{
+		ID:        uuid.New().String(),
+		Name:      request.GetName(),
+		DatasetID: request.GetDatasetID(),
+		Labels:    request.GetLabels(),
+	}
+
+	insertedValue := &models.ObjectHeritage{}
+
+	err := handler.RunTransaction(func(transactionHandler *DBUtilsHandler) error {
+		err := transactionHandler.reserveDatasetWrite(objectHeritage.GetDatasetID())
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		return transactionHandler.Insert(transactionHandler.GetObjectHeritageCollection(), &objectHeritage, insertedValue)
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return insertedValue, nil
+}
+
+//GetObjectHeritage Returns the object heritage with the given id
+func (handler *ObjectHeritageHandler) GetObjectHeritage(objectHeritageID string) (*models.ObjectHeritage, error) {
+	result := handler.GetObjectHeritageCollection().FindOne(handler.MongoDefaultContext, bson.M{
+		"ID": objectHeritageID,
+	})
+
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return nil, notFoundError(result.Err(), "ObjectHeritage", objectHeritageID)
+	}
+
+	objectHeritage := models.ObjectHeritage{}
+
+	err := result.Decode(&objectHeritage)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &objectHeritage, nil
+}
+
+//CheckObjectHeritageDataset Returns an error if the object heritage does not exist or belongs to another dataset
+func (handler *ObjectHeritageHandler) CheckObjectHeritageDataset(objectHeritageID string, datasetID string) error {
+	objectHeritage, err := handler.GetObjectHeritage(objectHeritageID)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if objectHeritage.GetDatasetID() != datasetID {
+		return apierrors.New(apierrors.FailedPrecondition, "Object heritage %v does not belong to dataset %v", objectHeritageID, datasetID)
+	}
+
+	return nil
+}
+
+//GetObjectHeritageObjectGroups Returns all object groups of an object heritage
+func (handler *ObjectHeritageHandler) GetObjectHeritageObjectGroups(objectHeritageID string) ([]*models.DatasetObjectGroup, error) {
+	var objectGroups []*models.DatasetObjectGroup
+
+	results, err := handler.GetDatasetObjectGroupCollection().Find(handler.MongoDefaultContext, bson.M{
+		"ObjectHeritageID": objectHeritageID,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = results.All(handler.MongoDefaultContext, &objectGroups)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return objectGroups, nil
+}
+
+//GetRelatedObjectGroups Returns all object groups that share the object heritage of the given object group
+//The given object group is part of the result
+func (handler *ObjectHeritageHandler) GetRelatedObjectGroups(objectGroupID string) ([]*models.DatasetObjectGroup, error) {
+	result := handler.GetDatasetObjectGroupCollection().FindOne(handler.MongoDefaultContext, bson.M{
+		"ID": objectGroupID,
+	})
+
+	if result.Err() != nil {
+		log.Println(result.Err().Error())
+		return nil, notFoundError(result.Err(), models.Resource_DatasetObjectGroupResource.String(), objectGroupID)
+	}
+
+	objectGroup := models.DatasetObjectGroup{}
+
+	err := result.Decode(&objectGroup)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if objectGroup.GetObjectHeritageID() == "" {
+		return []*models.DatasetObjectGroup{&objectGroup}, nil
+	}
+
+	return handler.GetObjectHeritageObjectGroups(objectGroup.GetObjectHeritageID())
+}
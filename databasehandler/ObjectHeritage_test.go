@@ -0,0 +1,66 @@
+package databasehandler
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/spf13/viper"
+)
+
+func TestObjectHeritageHandler_GetRelatedObjectGroups(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	testDataset := createTestDataset(t, "heritagedataset")
+
+	objectHeritageHandler, err := NewObjectHeritageHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectHeritage, err := objectHeritageHandler.CreateObjectHeritage(&services.CreateObjectHeritageRequest{
+		Name:      "heritage",
+		DatasetID: testDataset.GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = objectHeritageHandler.CheckObjectHeritageDataset(objectHeritage.GetID(), "otherdataset")
+	if err == nil {
+		t.Errorf("Object heritage was accepted for a foreign dataset")
+	}
+
+	var objectGroupIDs []string
+	for _, name := range []string{"release1", "release2"} {
+		objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+			Name:             name,
+			DatasetID:        testDataset.GetID(),
+			ObjectHeritageID: objectHeritage.GetID(),
+		}, "testproject")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		objectGroupIDs = append(objectGroupIDs, objectGroup.GetID())
+	}
+
+	relatedGroups, err := objectHeritageHandler.GetRelatedObjectGroups(objectGroupIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(relatedGroups) != 2 {
+		t.Errorf("Expected 2 related object groups, found %v", len(relatedGroups))
+	}
+
+	for _, group := range relatedGroups {
+		if group.GetObjectHeritageID() != objectHeritage.GetID() {
+			t.Errorf("Object group %v does not belong to object heritage %v", group.GetID(), objectHeritage.GetID())
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package databasehandler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//DefaultPageSize Number of entries in a page if no page size is requested
+const DefaultPageSize = 100
+
+//MaxPageSize Maximal number of entries in a page, larger page sizes are reduced to it
+const MaxPageSize = 1000
+
+//PageRequest Requests a single page of a list
+//An empty page token requests the first page, the following pages are requested with the token returned for the previous page
+//Lists that are requested without a page request, i.e. with a nil page, contain all entries
+type PageRequest struct {
+	PageSize  int64
+	PageToken string
+}
+
+//pageCursor The content of a page token
+//Entries are ordered by their ID, so a page starts after the last ID of the previous page
+type pageCursor struct {
+	LastID string `json:"LastID"`
+}
+
+//pageSize Returns the requested page size limited to MaxPageSize, zero if all entries are requested
+func (page *PageRequest) pageSize() int64 {
+	if page == nil {
+		return 0
+	}
+
+	if page.PageSize <= 0 {
+		return DefaultPageSize
+	}
+
+	if page.PageSize > MaxPageSize {
+		return MaxPageSize
+	}
+
+	return page.PageSize
+}
+
+//cursor Decodes the page token of the request
+func (page *PageRequest) cursor() (*pageCursor, error) {
+	if page == nil || page.PageToken == "" {
+		return nil, nil
+	}
+
+	tokenBytes, err := base64.RawURLEncoding.DecodeString(page.PageToken)
+	if err != nil {
+		return nil, apierrors.New(apierrors.InvalidArgument, "Invalid page token")
+	}
+
+	cursor := pageCursor{}
+
+	err = json.Unmarshal(tokenBytes, &cursor)
+	if err != nil || cursor.LastID == "" {
+		return nil, apierrors.New(apierrors.InvalidArgument, "Invalid page token")
+	}
+
+	return &cursor, nil
+}
+
+//encodePageToken Creates the opaque token for the page that follows the entry with the given ID
+func encodePageToken(lastID string) (string, error) {
+	tokenBytes, err := json.Marshal(&pageCursor{
+		LastID: lastID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+//pageEntryDecoder Decodes the current entry of a cursor into the page and returns its ID
+type pageEntryDecoder func(csr *mongo.Cursor) (string, error)
+
+//findPage Queries a single page of the entries of a collection that match the filter ordered by their ID
+//Every entry of the page is passed to decode, returns the token of the next page or an empty string for the last page
+//All matching entries are passed to decode if the page is nil
+func (handler *DBUtilsHandler) findPage(collection *mongo.Collection, filter bson.M, page *PageRequest, decode pageEntryDecoder) (string, error) {
+	cursor, err := page.cursor()
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	pageFilter := filter
+	if cursor != nil {
+		// The cursor is combined with the filter, so conditions of the filter on the ID still apply
+		pageFilter = bson.M{"$and": bson.A{
+			filter,
+			bson.M{"ID": bson.M{"$gt": cursor.LastID}},
+		}}
+	}
+
+	pageSize := page.pageSize()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "ID", Value: 1}})
+	if pageSize > 0 {
+		// One additional entry is requested to find out whether another page follows
+		findOptions.SetLimit(pageSize + 1)
+	}
+
+	csr, err := collection.Find(handler.MongoDefaultContext, pageFilter, findOptions)
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+	defer csr.Close(handler.MongoDefaultContext)
+
+	var decodedEntries int64
+	var lastID string
+
+	for csr.Next(handler.MongoDefaultContext) {
+		if pageSize > 0 && decodedEntries == pageSize {
+			return encodePageToken(lastID)
+		}
+
+		lastID, err = decode(csr)
+		if err != nil {
+			log.Println(err.Error())
+			return "", err
+		}
+
+		decodedEntries++
+	}
+
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return "", csr.Err()
+	}
+
+	return "", nil
+}
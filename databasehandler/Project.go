@@ -2,10 +2,10 @@ package databasehandler
 
 import (
 	"errors"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
 
@@ -13,6 +13,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 //ProjectActionHandler Handler for project related database functions
@@ -40,7 +41,7 @@ func (handler *ProjectActionHandler) CreateProject(userid string, request *servi
 	insertResults, err := handler.GetProjectCollection().InsertOne(handler.MongoDefaultContext, &project)
 	if err != nil {
 		log.Println(err.Error())
-		return nil, err
+		return nil, alreadyExistsError(err, "Project %v already exists", project.GetID())
 	}
 
 	var oid primitive.ObjectID
@@ -73,16 +74,27 @@ func (handler *ProjectActionHandler) CreateProject(userid string, request *servi
 }
 
 // AddUserToProject Add a user to a project
+// Fails if the user is already a member of the project
 func (handler *ProjectActionHandler) AddUserToProject(userID string, projectID string, rights []models.Right) (*models.ProjectEntry, error) {
+	if userID == "" {
+		return nil, apierrors.New(apierrors.InvalidArgument, "A user id has to be provided")
+	}
+
+	validatedRights, err := validateRights(rights)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	user := models.User{
 		UserID:   userID,
 		Resource: models.Resource_Project,
-		Rights:   rights,
+		Rights:   validatedRights,
 	}
 
-	_, err := handler.GetProjectCollection().UpdateOne(handler.MongoDefaultContext,
-		bson.M{"ID": projectID},
-		bson.M{"$addToSet": bson.M{"Users": &user}},
+	updateResult, err := handler.GetProjectCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{"ID": projectID, "Users.UserID": bson.M{"$ne": userID}},
+		bson.M{"$push": bson.M{"Users": &user}},
 	)
 	if err != nil {
 		log.Println(err.Error())
@@ -95,32 +107,95 @@ func (handler *ProjectActionHandler) AddUserToProject(userID string, projectID s
 		return nil, err
 	}
 
+	if updateResult.MatchedCount == 0 {
+		err := apierrors.New(apierrors.AlreadyExists, "User %v is already a member of project %v", userID, projectID)
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	return project, nil
 }
 
-// GetUserProjects Returns all projects of a user
-func (handler *ProjectActionHandler) GetUserProjects(userID string) ([]*models.ProjectEntry, error) {
-	queryResults, err := handler.GetProjectCollection().Find(handler.MongoDefaultContext, bson.M{"Users.UserID": userID})
+// RemoveUserFromProject Removes a user from a project
+// Fails if the user is the last member of the project with write rights
+func (handler *ProjectActionHandler) RemoveUserFromProject(userID string, projectID string) (*models.ProjectEntry, error) {
+	updateResult, err := handler.GetProjectCollection().UpdateOne(handler.MongoDefaultContext,
+		bson.M{
+			"ID":           projectID,
+			"Users.UserID": userID,
+			"Users":        remainingWriterFilter(userID),
+		},
+		bson.M{"$pull": bson.M{"Users": bson.M{"UserID": userID}}},
+	)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	var projects []*models.ProjectEntry
+	if updateResult.MatchedCount == 0 {
+		err := handler.membershipUpdateError(userID, projectID)
+		log.Println(err.Error())
+		return nil, err
+	}
 
-	for queryResults.Next(handler.MongoDefaultContext) {
-		project := models.ProjectEntry{}
+	return handler.GetProject(projectID)
+}
 
-		err := queryResults.Decode(&project)
-		if err != nil {
-			log.Println(err.Error())
-			return nil, err
-		}
+// ChangeUserRights Replaces the rights of a member of a project
+// Fails if the change would leave the project without a member with write rights
+func (handler *ProjectActionHandler) ChangeUserRights(userID string, projectID string, rights []models.Right) (*models.ProjectEntry, error) {
+	validatedRights, err := validateRights(rights)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	filter := bson.M{
+		"ID":           projectID,
+		"Users.UserID": userID,
+	}
+
+	if !containsRight(validatedRights, models.Right_Write) {
+		filter["Users"] = remainingWriterFilter(userID)
+	}
+
+	updateResult, err := handler.GetProjectCollection().UpdateOne(handler.MongoDefaultContext,
+		filter,
+		bson.M{"$set": bson.M{"Users.$[user].Rights": validatedRights}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"user.UserID": userID}},
+		}),
+	)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if updateResult.MatchedCount == 0 {
+		err := handler.membershipUpdateError(userID, projectID)
+		log.Println(err.Error())
+		return nil, err
+	}
 
+	return handler.GetProject(projectID)
+}
+
+// GetUserProjects Returns a page of the projects of a user and the token of the next page
+func (handler *ProjectActionHandler) GetUserProjects(userID string, page *PageRequest) ([]*models.ProjectEntry, string, error) {
+	var projects []*models.ProjectEntry
+
+	nextPageToken, err := handler.findPage(handler.GetProjectCollection(), bson.M{"Users.UserID": userID}, page, func(csr *mongo.Cursor) (string, error) {
+		project := models.ProjectEntry{}
+		err := csr.Decode(&project)
 		projects = append(projects, &project)
+		return project.GetID(), err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
 	}
 
-	return projects, nil
+	return projects, nextPageToken, nil
 }
 
 // GetProject Returns the project with the given project ID
@@ -128,7 +203,7 @@ func (handler *ProjectActionHandler) GetProject(projectID string) (*models.Proje
 	projectQueryResult := handler.GetProjectCollection().FindOne(handler.MongoDefaultContext, bson.M{"ID": projectID})
 	if projectQueryResult.Err() != nil {
 		log.Println(projectQueryResult.Err().Error())
-		return nil, projectQueryResult.Err()
+		return nil, notFoundError(projectQueryResult.Err(), models.Resource_Project.String(), projectID)
 	}
 
 	project := models.ProjectEntry{}
@@ -142,30 +217,42 @@ func (handler *ProjectActionHandler) GetProject(projectID string) (*models.Proje
 	return &project, nil
 }
 
-// GetProjectDatasets Returns all datasets of a project
-func (handler *ProjectActionHandler) GetProjectDatasets(projectID string) ([]*models.DatasetEntry, error) {
-	csr, err := handler.GetDatasetCollection().Find(handler.MongoDefaultContext, bson.M{
-		"ProjectID": projectID,
-	})
+// GetProjectIDs Returns the IDs of all projects
+func (handler *ProjectActionHandler) GetProjectIDs() ([]string, error) {
+	results, err := handler.GetProjectCollection().Distinct(handler.MongoDefaultContext, "ID", bson.M{})
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
+	var projectIDs []string
+	for _, result := range results {
+		if projectID, ok := result.(string); ok {
+			projectIDs = append(projectIDs, projectID)
+		}
+	}
+
+	return projectIDs, nil
+}
+
+// GetProjectDatasets Returns a page of the datasets of a project and the token of the next page
+func (handler *ProjectActionHandler) GetProjectDatasets(projectID string, page *PageRequest) ([]*models.DatasetEntry, string, error) {
 	var projectDatasets []*models.DatasetEntry
 
-	for csr.Next(handler.MongoDefaultContext) {
+	nextPageToken, err := handler.findPage(handler.GetDatasetCollection(), bson.M{
+		"ProjectID": projectID,
+	}, page, func(csr *mongo.Cursor) (string, error) {
 		dataset := models.DatasetEntry{}
 		err := csr.Decode(&dataset)
-		if err != nil {
-			log.Println(err.Error())
-			return nil, err
-		}
-
 		projectDatasets = append(projectDatasets, &dataset)
+		return dataset.GetID(), err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, "", err
 	}
 
-	return projectDatasets, nil
+	return projectDatasets, nextPageToken, nil
 }
 
 // UserCanAccessProject Checks if a user can access a specific project
@@ -175,9 +262,11 @@ func (handler *ProjectActionHandler) UserCanAccessProject(
 	projectID string) (bool, error) {
 
 	queryResults := handler.GetProjectCollection().FindOne(handler.MongoDefaultContext, bson.M{
-		"ID":           projectID,
-		"Users.UserID": userID,
-		"Users.Rights": requiredRight,
+		"ID": projectID,
+		"Users": bson.M{"$elemMatch": bson.M{
+			"UserID": userID,
+			"Rights": requiredRight,
+		}},
 	})
 
 	if queryResults.Err() != nil && queryResults.Err() != mongo.ErrNoDocuments {
@@ -192,20 +281,46 @@ func (handler *ProjectActionHandler) UserCanAccessProject(
 	return true, nil
 }
 
-// DeleteProject Deletes a project
+// DeleteProject Deletes a project and all of its datasets, dataset versions, object groups and object heritages
+// The datasets are marked as deleting and removed one after another, the project entry itself is removed last,
+// an interrupted deletion can be resumed by calling DeleteProject again
+// Objects in the object storage have to be removed separately
 func (handler *ProjectActionHandler) DeleteProject(projectID string) error {
-	datasets, err := handler.GetProjectDatasets(projectID)
+	_, err := handler.GetDatasetCollection().UpdateMany(handler.MongoDefaultContext,
+		bson.M{"ProjectID": projectID},
+		bson.M{"$set": bson.M{"Status": models.Status_Deleting}},
+	)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	datasetIDs, err := handler.GetDatasetCollection().Distinct(handler.MongoDefaultContext, "ID", bson.M{
+		"ProjectID": projectID,
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return err
 	}
 
-	if len(datasets) != 0 {
-		return fmt.Errorf("Project %v still has %v datasets associated with", projectID, len(datasets))
+	datasetHandler := DatasetActionHandler{
+		DBUtilsHandler: handler.DBUtilsHandler,
 	}
 
-	_, err = handler.GetProjectCollection().DeleteOne(handler.MongoDefaultContext, bson.M{"ID": projectID})
+	for _, datasetID := range datasetIDs {
+		id, ok := datasetID.(string)
+		if !ok {
+			continue
+		}
 
+		err := datasetHandler.deleteDatasetEntries(id)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	_, err = handler.GetProjectCollection().DeleteOne(handler.MongoDefaultContext, bson.M{"ID": projectID})
 	if err != nil {
 		log.Println(err.Error())
 		return err
@@ -213,3 +328,62 @@ func (handler *ProjectActionHandler) DeleteProject(projectID string) error {
 
 	return nil
 }
+
+//remainingWriterFilter Matches projects in which a user other than the given one holds write rights
+func remainingWriterFilter(userID string) bson.M {
+	return bson.M{"$elemMatch": bson.M{
+		"UserID": bson.M{"$ne": userID},
+		"Rights": models.Right_Write,
+	}}
+}
+
+//membershipUpdateError Determines why a membership update of a project did not match
+func (handler *ProjectActionHandler) membershipUpdateError(userID string, projectID string) error {
+	project, err := handler.GetProject(projectID)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range project.GetUsers() {
+		if user.GetUserID() == userID {
+			return apierrors.New(apierrors.FailedPrecondition, "Project %v requires at least one other user with %v rights", projectID, models.Right_Write)
+		}
+	}
+
+	return apierrors.New(apierrors.NotFound, "User %v is not a member of project %v", userID, projectID)
+}
+
+func containsRight(rights []models.Right, right models.Right) bool {
+	for _, containedRight := range rights {
+		if containedRight == right {
+			return true
+		}
+	}
+
+	return false
+}
+
+//validateRights Checks that at least one known right is given and removes duplicates
+func validateRights(rights []models.Right) ([]models.Right, error) {
+	if len(rights) == 0 {
+		return nil, apierrors.New(apierrors.InvalidArgument, "At least one right has to be provided")
+	}
+
+	var validatedRights []models.Right
+	seenRights := make(map[models.Right]bool)
+
+	for _, right := range rights {
+		if _, ok := models.Right_name[int32(right)]; !ok {
+			return nil, apierrors.New(apierrors.InvalidArgument, "Unknown right: %v", right)
+		}
+
+		if seenRights[right] {
+			continue
+		}
+
+		seenRights[right] = true
+		validatedRights = append(validatedRights, right)
+	}
+
+	return validatedRights, nil
+}
@@ -0,0 +1,344 @@
+package databasehandler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestProjectActionHandler_DeleteProject(t *testing.T) {
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler, err := NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name:        "deleteproject",
+		Description: "project to delete",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "deletedataset",
+		Datatype:    "txt",
+		ProjectID:   project.GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "foo",
+		DatasetID: dataset.GetID(),
+		Objects: []*services.CreateObjectRequest{
+			{
+				Filename:   "testfile",
+				Filetype:   "txt",
+				ContentLen: 9,
+			},
+		},
+	}, project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = projectHandler.DeleteProject(project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.GetProject(project.GetID())
+	if err == nil {
+		t.Errorf("Project %v still exists after deletion", project.GetID())
+	}
+
+	_, err = objectGroupHandler.GetObjectGroup(objectGroup.GetID())
+	if err == nil {
+		t.Errorf("Object group %v still exists after project deletion", objectGroup.GetID())
+	}
+
+	datasets, _, err := projectHandler.GetProjectDatasets(project.GetID(), nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(datasets) != 0 {
+		t.Errorf("Found %v datasets after project deletion", len(datasets))
+	}
+
+	err = projectHandler.DeleteProject(project.GetID())
+	if err != nil {
+		t.Errorf("Deleting an already deleted project should succeed: %v", err)
+	}
+}
+
+func TestProjectActionHandler_ResumeDeleteProject(t *testing.T) {
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	datasetHandler := DatasetActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name: "resumedeleteproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var datasetIDs []string
+	for i := 0; i < 2; i++ {
+		dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+			DatasetName: fmt.Sprintf("resumedeletedataset%v", i),
+			ProjectID:   project.GetID(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		datasetIDs = append(datasetIDs, dataset.GetID())
+	}
+
+	// A deletion that was interrupted after the first dataset was removed
+	_, err = dbHandler.GetDatasetCollection().UpdateMany(dbHandler.MongoDefaultContext,
+		bson.M{"ProjectID": project.GetID()},
+		bson.M{"$set": bson.M{"Status": models.Status_Deleting}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = datasetHandler.deleteDatasetEntries(datasetIDs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remainingDataset, err := datasetHandler.GetDataset(datasetIDs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remainingDataset.GetStatus() != models.Status_Deleting {
+		t.Errorf("Dataset of a project that is being deleted is in status %v", remainingDataset.GetStatus())
+	}
+
+	err = projectHandler.DeleteProject(project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.GetProject(project.GetID())
+	if err == nil {
+		t.Errorf("Project %v still exists after the deletion was resumed", project.GetID())
+	}
+
+	_, err = datasetHandler.GetDataset(datasetIDs[1])
+	if err == nil {
+		t.Errorf("Dataset %v still exists after the deletion was resumed", datasetIDs[1])
+	}
+}
+
+func TestProjectActionHandler_AddUserToProject(t *testing.T) {
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name:        "userproject",
+		Description: "project to add users to",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updatedProject, err := projectHandler.AddUserToProject("seconduser", project.GetID(), []models.Right{models.Right_Read, models.Right_Read})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updatedProject.GetUsers()) != 2 {
+		t.Errorf("Expected 2 users in project, found %v", len(updatedProject.GetUsers()))
+	}
+
+	for _, user := range updatedProject.GetUsers() {
+		if user.GetUserID() == "seconduser" && len(user.GetRights()) != 1 {
+			t.Errorf("Duplicate rights were not removed: %v", user.GetRights())
+		}
+	}
+
+	_, err = projectHandler.AddUserToProject("seconduser", project.GetID(), []models.Right{models.Right_Write})
+	if err == nil {
+		t.Errorf("User was added twice to the same project")
+	}
+
+	canWrite, err := projectHandler.UserCanAccessProject(models.Right_Write, "seconduser", project.GetID())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if canWrite {
+		t.Errorf("User with read rights was granted write access")
+	}
+}
+
+func TestProjectActionHandler_RemoveUserAndChangeRights(t *testing.T) {
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	project, err := projectHandler.CreateProject("owner", &services.CreateProjectRequest{
+		Name:        "membershipproject",
+		Description: "project to change memberships",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.ChangeUserRights("owner", project.GetID(), []models.Right{models.Right_Read})
+	if err == nil {
+		t.Errorf("Last user with write rights lost write rights")
+	}
+
+	_, err = projectHandler.RemoveUserFromProject("owner", project.GetID())
+	if err == nil {
+		t.Errorf("Last user with write rights was removed")
+	}
+
+	_, err = projectHandler.AddUserToProject("member", project.GetID(), []models.Right{models.Right_Read})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.ChangeUserRights("member", project.GetID(), []models.Right{models.Right_Read, models.Right_Write})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	canWrite, err := projectHandler.UserCanAccessProject(models.Right_Write, "member", project.GetID())
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !canWrite {
+		t.Errorf("Changed rights were not applied")
+	}
+
+	updatedProject, err := projectHandler.RemoveUserFromProject("owner", project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updatedProject.GetUsers()) != 1 || updatedProject.GetUsers()[0].GetUserID() != "member" {
+		t.Errorf("Unexpected users after removal: %v", updatedProject.GetUsers())
+	}
+
+	_, err = projectHandler.RemoveUserFromProject("owner", project.GetID())
+	if err == nil {
+		t.Errorf("Removing a user that is not a member should fail")
+	}
+}
+
+func TestProjectActionHandler_GetProjectDatasetsPaged(t *testing.T) {
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	datasetHandler, err := NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name:        "pagedproject",
+		Description: "project with paged datasets",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createdDatasets := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+			DatasetName: fmt.Sprintf("pageddataset%v", i),
+			Datatype:    "txt",
+			ProjectID:   project.GetID(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		createdDatasets[dataset.GetID()] = true
+	}
+
+	page := &PageRequest{PageSize: 2}
+	listedDatasets := make(map[string]bool)
+	pages := 0
+
+	for {
+		datasets, nextPageToken, err := projectHandler.GetProjectDatasets(project.GetID(), page)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages++
+
+		if len(datasets) > 2 {
+			t.Errorf("Page contains %v datasets, expected at most 2", len(datasets))
+		}
+
+		for _, dataset := range datasets {
+			if listedDatasets[dataset.GetID()] {
+				t.Errorf("Dataset %v was listed twice", dataset.GetID())
+			}
+			listedDatasets[dataset.GetID()] = true
+		}
+
+		if nextPageToken == "" {
+			break
+		}
+
+		page.PageToken = nextPageToken
+	}
+
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %v", pages)
+	}
+
+	if len(listedDatasets) != len(createdDatasets) {
+		t.Errorf("Expected %v datasets, listed %v", len(createdDatasets), len(listedDatasets))
+	}
+
+	allDatasets, nextPageToken, err := projectHandler.GetProjectDatasets(project.GetID(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allDatasets) != len(createdDatasets) || nextPageToken != "" {
+		t.Errorf("Expected all %v datasets without page request, got %v and next page token %q", len(createdDatasets), len(allDatasets), nextPageToken)
+	}
+
+	_, _, err = projectHandler.GetProjectDatasets(project.GetID(), &PageRequest{PageToken: "invalid"})
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for invalid page token, got: %v", err)
+	}
+}
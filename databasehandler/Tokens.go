@@ -2,29 +2,164 @@ package databasehandler
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/google/uuid"
+	"github.com/spf13/viper"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const tokenLen = 64
 
+//tokenSaltLen Length of the random salt that is hashed together with a token
+const tokenSaltLen = 16
+
+//tokenPrefixLen Number of leading characters of a token that are stored in plaintext to look the token up
+//The remaining characters still carry more than 400 bits of entropy
+const tokenPrefixLen = 12
+
+//defaultTokenLifetime Maximal lifetime of API tokens if none is configured
+const defaultTokenLifetime = 365 * 24 * time.Hour
+
+//APITokenEntry The database entry of an API token
+//In addition to the fields of the API model it stores the ID of the project or dataset the token is scoped to
+//The token itself is not stored, only its prefix for the lookup and a salted hash to verify it
+type APITokenEntry struct {
+	ID          string                 `json:"ID"`
+	UserID      *models.User           `json:"UserID"`
+	TokenPrefix string                 `json:"TokenPrefix"`
+	TokenSalt   []byte                 `json:"TokenSalt"`
+	TokenHash   []byte                 `json:"TokenHash"`
+	Resource    models.Resource        `json:"Resource"`
+	ResourceID  string                 `json:"ResourceID"`
+	Created     *timestamppb.Timestamp `json:"Created"`
+	Expires     *timestamppb.Timestamp `json:"Expires"`
+}
+
+//plaintextAPITokenEntry The part of a token entry created before tokens were hashed that is required to migrate it
+type plaintextAPITokenEntry struct {
+	ID    string `json:"ID"`
+	Token string `json:"Token"`
+}
+
+//TokenEntry Converts the database entry into the API model, the returned entry never contains the token itself
+func (entry *APITokenEntry) TokenEntry() *models.TokenEntry {
+	return &models.TokenEntry{
+		ID:       entry.ID,
+		UserID:   entry.UserID,
+		Resource: entry.Resource,
+		Created:  entry.Created,
+		Expires:  entry.Expires,
+	}
+}
+
+//setToken Stores the prefix and a newly salted hash of the token in the entry
+func (entry *APITokenEntry) setToken(accessToken string) error {
+	salt := make([]byte, tokenSaltLen)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return err
+	}
+
+	entry.TokenPrefix = tokenPrefix(accessToken)
+	entry.TokenSalt = salt
+	entry.TokenHash = hashToken(salt, accessToken)
+
+	return nil
+}
+
+//matchesToken Checks whether the token matches the stored hash in constant time
+func (entry *APITokenEntry) matchesToken(accessToken string) bool {
+	return subtle.ConstantTimeCompare(entry.TokenHash, hashToken(entry.TokenSalt, accessToken)) == 1
+}
+
+//tokenPrefix Returns the non-secret part of a token that is used to look it up
+func tokenPrefix(accessToken string) string {
+	if len(accessToken) < tokenPrefixLen {
+		return accessToken
+	}
+
+	return accessToken[:tokenPrefixLen]
+}
+
+//hashToken Hashes a salted token
+//Tokens are long random strings, so a fast hash is sufficient and no key derivation function is required
+func hashToken(salt []byte, accessToken string) []byte {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(accessToken))
+	return hash.Sum(nil)
+}
+
+//IsExpired Checks whether the token is expired, tokens without expiry time do not expire
+func (entry *APITokenEntry) IsExpired() bool {
+	return entry.Expires != nil && !time.Now().Before(entry.Expires.AsTime())
+}
+
 //TokenActionHandler Handler for token related database actions
 type TokenActionHandler struct {
 	*DBUtilsHandler
+	MaxLifetime time.Duration
+}
+
+//NewTokenActionHandler Creates a new token handler, the maximal lifetime of tokens is read from Config.APITokens.MaxLifetime
+func NewTokenActionHandler(dbUtilsHandler *DBUtilsHandler) (*TokenActionHandler, error) {
+	handler := TokenActionHandler{
+		DBUtilsHandler: dbUtilsHandler,
+		MaxLifetime:    viper.GetDuration("Config.APITokens.MaxLifetime"),
+	}
+
+	return &handler, nil
 }
 
-// CreateToken Creates a token for a project
-func (handler *TokenActionHandler) CreateToken(userID string, rights []models.Right, resource models.Resource) (*models.TokenEntry, error) {
+//maxLifetime Returns the configured maximal lifetime of tokens or defaultTokenLifetime if none is configured
+func (handler *TokenActionHandler) maxLifetime() time.Duration {
+	if handler.MaxLifetime <= 0 {
+		return defaultTokenLifetime
+	}
+
+	return handler.MaxLifetime
+}
+
+// CreateToken Creates a token for a user that is scoped to the project or dataset given in the request
+// Tokens expire after the maximal lifetime at the latest, also if no or a later expiry time is requested
+func (handler *TokenActionHandler) CreateToken(userID string, request *models.CreateTokenRequest) (*models.TokenEntry, error) {
+	if request.GetResource() != models.Resource_Project && request.GetResource() != models.Resource_Dataset {
+		return nil, apierrors.New(apierrors.InvalidArgument, "API tokens can only be scoped to a %v or a %v", models.Resource_Project, models.Resource_Dataset)
+	}
+
+	if request.GetResourceID() == "" {
+		return nil, apierrors.New(apierrors.InvalidArgument, "A resource id has to be provided")
+	}
+
+	rights, err := validateRights(request.GetRights())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	now := time.Now()
+	expireTime := timestamppb.New(now.Add(handler.maxLifetime()))
+	if request.GetExpires() != nil {
+		if !now.Before(request.GetExpires().AsTime()) {
+			return nil, apierrors.New(apierrors.InvalidArgument, "The expiry time of a token has to be in the future")
+		}
+
+		if request.GetExpires().AsTime().Before(expireTime.AsTime()) {
+			expireTime = request.GetExpires()
+		}
+	}
+
 	b := make([]byte, tokenLen)
-	_, err := rand.Read(b)
+	_, err = rand.Read(b)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -34,20 +169,24 @@ func (handler *TokenActionHandler) CreateToken(userID string, rights []models.Ri
 	secretString := base64.RawStdEncoding.EncodeToString(b)
 
 	user := models.User{
-		Resource: resource,
+		Resource: request.GetResource(),
 		Rights:   rights,
 		UserID:   userID,
 	}
 
-	expireTime := timestamppb.New(time.Now().Add(365 * 24 * time.Hour))
+	token := APITokenEntry{
+		ID:         uuidString,
+		Created:    timestamppb.Now(),
+		UserID:     &user,
+		Expires:    expireTime,
+		Resource:   request.GetResource(),
+		ResourceID: request.GetResourceID(),
+	}
 
-	token := models.TokenEntry{
-		ID:       uuidString,
-		Created:  timestamppb.Now(),
-		Token:    secretString,
-		UserID:   &user,
-		Expires:  expireTime,
-		Resource: resource,
+	err = token.setToken(secretString)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
 	insertResult, err := handler.GetTokenCollection().InsertOne(handler.MongoDefaultContext, &token)
@@ -56,7 +195,7 @@ func (handler *TokenActionHandler) CreateToken(userID string, rights []models.Ri
 		return nil, err
 	}
 
-	insertedToken := models.TokenEntry{}
+	insertedToken := APITokenEntry{}
 
 	err = handler.parseInsertResult(insertResult, &insertedToken, handler.GetTokenCollection())
 	if err != nil {
@@ -64,76 +203,211 @@ func (handler *TokenActionHandler) CreateToken(userID string, rights []models.Ri
 		return nil, err
 	}
 
-	return &insertedToken, nil
+	// The token is only returned on creation, afterwards only its hash is known
+	tokenEntry := insertedToken.TokenEntry()
+	tokenEntry.Token = secretString
+
+	return tokenEntry, nil
 }
 
-//ValidateTokenForResourceAction Validates an action on a specific resource from a token
+//GetAPIToken Returns the database entry of a token, fails for unknown and expired tokens
+//Only hashed tokens are accepted, tokens stored in plaintext are replaced by their hashes by the schema migrations on startup
+func (handler *TokenActionHandler) GetAPIToken(accessToken string) (*APITokenEntry, error) {
+	if accessToken == "" {
+		return nil, apierrors.New(apierrors.Unauthenticated, "Invalid API token")
+	}
+
+	token, err := handler.findAPIToken(accessToken)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if token.IsExpired() {
+		return nil, apierrors.New(apierrors.Unauthenticated, "API token %v is expired", token.ID)
+	}
+
+	return token, nil
+}
+
+//ValidateTokenForResourceAction Validates an action on a resource with an API token
+//The resource is given by the IDs of its project and, if it belongs to one, its dataset
+//Project scoped tokens are valid for all resources of the project, dataset scoped tokens only for the resources of the dataset
+//The user of the token has to be a member of the project with the required right, so tokens lose their access with the membership
 func (handler *TokenActionHandler) ValidateTokenForResourceAction(
-	token string, resourceID string,
-	ressourceType models.Resource,
+	accessToken string,
+	projectID string,
+	datasetID string,
 	requiredRight models.Right) (bool, error) {
 
-	authorized := false
+	token, err := handler.GetAPIToken(accessToken)
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	if !containsRight(token.UserID.GetRights(), requiredRight) {
+		return false, nil
+	}
+
+	var tokenResourceID string
+	switch token.Resource {
+	case models.Resource_Project:
+		tokenResourceID = projectID
+	case models.Resource_Dataset:
+		tokenResourceID = datasetID
+	default:
+		return false, nil
+	}
+
+	if token.ResourceID == "" || token.ResourceID != tokenResourceID {
+		return false, nil
+	}
 
-	ressourceType = models.Resource_Project
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: handler.DBUtilsHandler,
+	}
+
+	isMember, err := projectHandler.UserCanAccessProject(requiredRight, token.UserID.GetUserID(), projectID)
+	if err != nil {
+		log.Println(err.Error())
+		return false, err
+	}
+
+	return isMember, nil
+}
 
-	result := handler.GetTokenCollection().FindOne(handler.MongoDefaultContext, bson.M{
-		"Token":         token,
-		"UserID.Rights": requiredRight,
-		"Resource":      ressourceType,
+//findAPIToken Looks up a hashed token by its prefix and verifies it against the stored hash
+func (handler *TokenActionHandler) findAPIToken(accessToken string) (*APITokenEntry, error) {
+	csr, err := handler.GetTokenCollection().Find(handler.MongoDefaultContext, bson.M{
+		"TokenPrefix": tokenPrefix(accessToken),
 	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+	defer csr.Close(handler.MongoDefaultContext)
+
+	for csr.Next(handler.MongoDefaultContext) {
+		token := APITokenEntry{}
+
+		err := csr.Decode(&token)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
 
-	if result.Err() != nil && result.Err() != mongo.ErrNoDocuments {
-		log.Println(result.Err().Error())
-		return false, result.Err()
+		if token.matchesToken(accessToken) {
+			return &token, nil
+		}
 	}
 
-	if result.Err() == mongo.ErrNoDocuments {
-		return false, nil
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return nil, csr.Err()
+	}
+
+	return nil, apierrors.New(apierrors.Unauthenticated, "Invalid API token")
+}
+
+//hashPlaintextToken Stores the hash of a plaintext token entry and removes the plaintext
+func (handler *TokenActionHandler) hashPlaintextToken(plaintextToken *plaintextAPITokenEntry) error {
+	hashedToken := APITokenEntry{}
+
+	err := hashedToken.setToken(plaintextToken.Token)
+	if err != nil {
+		log.Println(err.Error())
+		return err
 	}
 
-	authorized = true
+	// Matching the plaintext token as well ensures that concurrent migrations of the same entry do not overwrite each other
+	_, err = handler.GetTokenCollection().UpdateOne(handler.MongoDefaultContext, bson.M{
+		"ID":    plaintextToken.ID,
+		"Token": plaintextToken.Token,
+	}, bson.M{
+		"$set": bson.M{
+			"TokenPrefix": hashedToken.TokenPrefix,
+			"TokenSalt":   hashedToken.TokenSalt,
+			"TokenHash":   hashedToken.TokenHash,
+		},
+		"$unset": bson.M{
+			"Token": "",
+		},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
 
-	return authorized, nil
+	return nil
 }
 
-// GetTokenUser Returns the user of this token
-func (handler *TokenActionHandler) GetTokenUser(accessToken string) (*models.TokenEntry, error) {
-	queryResults := handler.GetTokenCollection().FindOne(handler.MongoDefaultContext, bson.M{
-		"Token": accessToken,
+//MigratePlaintextTokens Replaces all tokens that are still stored in plaintext by their hashes
+//Returns the number of migrated tokens
+func (handler *TokenActionHandler) MigratePlaintextTokens() (int, error) {
+	csr, err := handler.GetTokenCollection().Find(handler.MongoDefaultContext, bson.M{
+		"Token": bson.M{"$exists": true, "$ne": ""},
 	})
+	if err != nil {
+		log.Println(err.Error())
+		return 0, err
+	}
+	defer csr.Close(handler.MongoDefaultContext)
+
+	migrated := 0
+
+	for csr.Next(handler.MongoDefaultContext) {
+		plaintextToken := plaintextAPITokenEntry{}
+
+		err := csr.Decode(&plaintextToken)
+		if err != nil {
+			log.Println(err.Error())
+			return migrated, err
+		}
 
-	if queryResults.Err() != nil {
-		log.Println(queryResults.Err().Error())
-		return nil, queryResults.Err()
+		err = handler.hashPlaintextToken(&plaintextToken)
+		if err != nil {
+			log.Println(err.Error())
+			return migrated, err
+		}
+
+		migrated++
 	}
 
-	var token models.TokenEntry
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return migrated, csr.Err()
+	}
 
-	err := queryResults.Decode(&token)
+	return migrated, nil
+}
+
+// GetTokenUser Returns the user of this token
+func (handler *TokenActionHandler) GetTokenUser(accessToken string) (*models.TokenEntry, error) {
+	token, err := handler.GetAPIToken(accessToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	return &token, err
+	return token.TokenEntry(), nil
 }
 
-// GetUserDatasetTokens Returns all tokens of a user for a dataset
-func (handler *TokenActionHandler) GetUserDatasetTokens(userID string) ([]*models.TokenEntry, error) {
+// GetUserTokens Returns all tokens of a user, the secrets of the tokens are not included
+func (handler *TokenActionHandler) GetUserTokens(userID string) ([]*models.TokenEntry, error) {
 	csr, err := handler.GetTokenCollection().Find(handler.MongoDefaultContext, bson.M{
 		"UserID.UserID": userID,
 	})
-
-	if err != nil && err != mongo.ErrNoDocuments {
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
+	defer csr.Close(handler.MongoDefaultContext)
 
 	var tokens []*models.TokenEntry
 
 	for csr.Next(handler.MongoDefaultContext) {
-		token := models.TokenEntry{}
+		token := APITokenEntry{}
 
 		err := csr.Decode(&token)
 		if err != nil {
@@ -141,8 +415,31 @@ func (handler *TokenActionHandler) GetUserDatasetTokens(userID string) ([]*model
 			return nil, err
 		}
 
-		tokens = append(tokens, &token)
+		tokens = append(tokens, token.TokenEntry())
+	}
+
+	if csr.Err() != nil {
+		log.Println(csr.Err().Error())
+		return nil, csr.Err()
 	}
 
 	return tokens, nil
 }
+
+// RevokeToken Deletes a token of a user
+func (handler *TokenActionHandler) RevokeToken(userID string, tokenID string) error {
+	deleteResult, err := handler.GetTokenCollection().DeleteOne(handler.MongoDefaultContext, bson.M{
+		"ID":            tokenID,
+		"UserID.UserID": userID,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if deleteResult.DeletedCount == 0 {
+		return apierrors.NewNotFound("APIToken", tokenID)
+	}
+
+	return nil
+}
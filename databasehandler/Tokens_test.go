@@ -0,0 +1,275 @@
+package databasehandler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestTokenActionHandler_ValidateTokenForResourceAction(t *testing.T) {
+	tokenHandler := TokenActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name: "tokenproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := tokenHandler.CreateToken("testuser", &models.CreateTokenRequest{
+		ResourceID: "tokendataset",
+		Resource:   models.Resource_Dataset,
+		Rights:     []models.Right{models.Right_Read},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized, err := tokenHandler.ValidateTokenForResourceAction(token.GetToken(), project.GetID(), "tokendataset", models.Right_Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !authorized {
+		t.Errorf("Token was not authorized for its own dataset")
+	}
+
+	authorized, err = tokenHandler.ValidateTokenForResourceAction(token.GetToken(), project.GetID(), "tokendataset", models.Right_Write)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorized {
+		t.Errorf("Read token was authorized to write")
+	}
+
+	authorized, err = tokenHandler.ValidateTokenForResourceAction(token.GetToken(), project.GetID(), "otherdataset", models.Right_Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorized {
+		t.Errorf("Dataset token was authorized for another dataset")
+	}
+
+	authorized, err = tokenHandler.ValidateTokenForResourceAction(token.GetToken(), project.GetID(), "", models.Right_Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorized {
+		t.Errorf("Dataset token was authorized for its project")
+	}
+
+	_, err = projectHandler.AddUserToProject("otheruser", project.GetID(), []models.Right{models.Right_Read, models.Right_Write})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = projectHandler.RemoveUserFromProject("testuser", project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized, err = tokenHandler.ValidateTokenForResourceAction(token.GetToken(), project.GetID(), "tokendataset", models.Right_Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorized {
+		t.Errorf("Token was authorized after its user was removed from the project")
+	}
+
+	_, err = tokenHandler.CreateToken("testuser", &models.CreateTokenRequest{
+		ResourceID: "tokenproject",
+		Resource:   models.Resource_Project,
+		Rights:     []models.Right{models.Right_Read},
+		Expires:    timestamppb.New(time.Now().Add(-time.Hour)),
+	})
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for expired token, got: %v", err)
+	}
+
+	_, err = tokenHandler.ValidateTokenForResourceAction("unknowntoken", project.GetID(), "tokendataset", models.Right_Read)
+	if !apierrors.Is(err, apierrors.Unauthenticated) {
+		t.Errorf("Expected unauthenticated error for unknown token, got: %v", err)
+	}
+}
+
+func TestTokenActionHandler_CreateTokenMaxLifetime(t *testing.T) {
+	tokenHandler := TokenActionHandler{
+		DBUtilsHandler: dbHandler,
+		MaxLifetime:    time.Hour,
+	}
+
+	for _, expires := range []*timestamppb.Timestamp{nil, timestamppb.New(time.Now().Add(48 * time.Hour))} {
+		token, err := tokenHandler.CreateToken("testuser", &models.CreateTokenRequest{
+			ResourceID: "tokenproject",
+			Resource:   models.Resource_Project,
+			Rights:     []models.Right{models.Right_Read},
+			Expires:    expires,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token.GetExpires().AsTime().After(time.Now().Add(time.Hour)) {
+			t.Errorf("Token expires at %v, after the maximal lifetime", token.GetExpires().AsTime())
+		}
+	}
+
+	requestedExpiry := timestamppb.New(time.Now().Add(time.Minute).Truncate(time.Millisecond))
+	token, err := tokenHandler.CreateToken("testuser", &models.CreateTokenRequest{
+		ResourceID: "tokenproject",
+		Resource:   models.Resource_Project,
+		Rights:     []models.Right{models.Right_Read},
+		Expires:    requestedExpiry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !token.GetExpires().AsTime().Equal(requestedExpiry.AsTime()) {
+		t.Errorf("Expected requested expiry time %v within the maximal lifetime, got %v", requestedExpiry.AsTime(), token.GetExpires().AsTime())
+	}
+}
+
+func TestTokenActionHandler_RevokeToken(t *testing.T) {
+	tokenHandler := TokenActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	token, err := tokenHandler.CreateToken("revokeuser", &models.CreateTokenRequest{
+		ResourceID: "revokeproject",
+		Resource:   models.Resource_Project,
+		Rights:     []models.Right{models.Right_Read},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := tokenHandler.GetUserTokens("revokeuser")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 1 || tokens[0].GetID() != token.GetID() {
+		t.Fatalf("Expected the created token to be listed, got: %v", tokens)
+	}
+
+	if tokens[0].GetToken() != "" {
+		t.Errorf("Listed token contains its secret")
+	}
+
+	err = tokenHandler.RevokeToken("otheruser", token.GetID())
+	if !apierrors.Is(err, apierrors.NotFound) {
+		t.Errorf("Expected not found error when revoking the token of another user, got: %v", err)
+	}
+
+	err = tokenHandler.RevokeToken("revokeuser", token.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tokenHandler.GetAPIToken(token.GetToken())
+	if !apierrors.Is(err, apierrors.Unauthenticated) {
+		t.Errorf("Expected unauthenticated error for revoked token, got: %v", err)
+	}
+}
+
+func TestTokenActionHandler_MigratePlaintextTokens(t *testing.T) {
+	tokenHandler := TokenActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	projectHandler := ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	project, err := projectHandler.CreateProject("migrationuser", &services.CreateProjectRequest{
+		Name: "migrationproject",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := tokenHandler.CreateToken("migrationuser", &models.CreateTokenRequest{
+		ResourceID: project.GetID(),
+		Resource:   models.Resource_Project,
+		Rights:     []models.Right{models.Right_Read},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storedToken := bson.M{}
+	err = tokenHandler.GetTokenCollection().FindOne(tokenHandler.MongoDefaultContext, bson.M{"ID": token.GetID()}).Decode(&storedToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := storedToken["Token"]; ok {
+		t.Errorf("Token was stored in plaintext")
+	}
+
+	plaintextTokens := []string{"plaintexttoken1", "plaintexttoken2"}
+	for _, plaintextToken := range plaintextTokens {
+		_, err = tokenHandler.GetTokenCollection().InsertOne(tokenHandler.MongoDefaultContext, bson.M{
+			"ID":         uuid.New().String(),
+			"UserID":     bson.M{"UserID": "migrationuser", "Rights": bson.A{models.Right_Read}},
+			"Token":      plaintextToken,
+			"Resource":   models.Resource_Project,
+			"ResourceID": project.GetID(),
+			"Expires":    timestamppb.New(time.Now().Add(time.Hour)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = tokenHandler.GetAPIToken(plaintextTokens[0])
+	if !apierrors.Is(err, apierrors.Unauthenticated) {
+		t.Errorf("Expected unauthenticated error for plaintext token before the migration, got: %v", err)
+	}
+
+	migrated, err := tokenHandler.MigratePlaintextTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if migrated != len(plaintextTokens) {
+		t.Errorf("Expected %v plaintext tokens to be migrated, got %v", len(plaintextTokens), migrated)
+	}
+
+	for _, plaintextToken := range plaintextTokens {
+		authorized, err := tokenHandler.ValidateTokenForResourceAction(plaintextToken, project.GetID(), "", models.Right_Read)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !authorized {
+			t.Errorf("Migrated token %v was not authorized", plaintextToken)
+		}
+	}
+
+	count, err := tokenHandler.GetTokenCollection().CountDocuments(tokenHandler.MongoDefaultContext, bson.M{"Token": bson.M{"$exists": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != 0 {
+		t.Errorf("Expected no plaintext tokens after migration, found %v", count)
+	}
+}
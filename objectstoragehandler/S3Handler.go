@@ -2,22 +2,40 @@ package objectstoragehandler
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/spf13/viper"
 )
 
+//defaultMultipartPartSize Part size used for multipart uploads if none is configured
+const defaultMultipartPartSize = 64 * 1024 * 1024
+
+//minMultipartPartSize The minimal size of all but the last part of a multipart upload allowed by S3
+const minMultipartPartSize = 5 * 1024 * 1024
+
+//maxMultipartParts The maximal number of parts of a multipart upload allowed by S3
+const maxMultipartParts = 10000
+
 type S3Handler struct {
-	S3Client      *s3.Client
-	PresignClient *s3.PresignClient
-	S3Endpoint    string
+	S3Client          *s3.Client
+	PresignClient     *s3.PresignClient
+	S3Endpoint        string
+	MultipartPartSize int64
 }
 
 //NewS3Handler Creates a new  handler to handle S3 related operations
@@ -56,10 +74,22 @@ func NewS3Handler() (*S3Handler, error) {
 
 	presignClient := s3.NewPresignClient(client)
 
+	partSize := viper.GetInt64("Config.S3.MultipartPartSize")
+	if partSize == 0 {
+		partSize = defaultMultipartPartSize
+	}
+
+	if partSize < minMultipartPartSize {
+		err := fmt.Errorf("Config.S3.MultipartPartSize has to be at least %v bytes", minMultipartPartSize)
+		log.Println(err.Error())
+		return nil, err
+	}
+
 	handler := S3Handler{
-		S3Client:      client,
-		PresignClient: presignClient,
-		S3Endpoint:    endpoint,
+		S3Client:          client,
+		PresignClient:     presignClient,
+		S3Endpoint:        endpoint,
+		MultipartPartSize: partSize,
 	}
 
 	return &handler, nil
@@ -94,3 +124,286 @@ func (s3handler *S3Handler) CreatePresignedDownloadLink(object *models.DatasetOb
 
 	return presignedRequestURL.URL, nil
 }
+
+// GetObjectSize Returns the size of an object in the object storage
+// The returned bool is false if the object does not exist in the object storage
+func (s3handler *S3Handler) GetObjectSize(object *models.DatasetObjectEntry) (int64, bool, error) {
+	output, err := s3handler.S3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(object.GetLocation().GetBucket()),
+		Key:    aws.String(object.GetLocation().GetKey()),
+	})
+
+	var responseError *smithyhttp.ResponseError
+	if errors.As(err, &responseError) && responseError.HTTPStatusCode() == http.StatusNotFound {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		return 0, false, err
+	}
+
+	return output.ContentLength, true, nil
+}
+
+// DeleteObjectsWithPrefix Deletes all objects in a bucket whose key starts with the given prefix
+func (s3handler *S3Handler) DeleteObjectsWithPrefix(bucket string, prefix string) error {
+	return s3handler.ListObjectPages(bucket, prefix, func(objects []types.Object) error {
+		var keys []string
+		for _, object := range objects {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+
+		return s3handler.DeleteObjects(bucket, keys)
+	})
+}
+
+// ListObjectPages Calls handlePage for every page of objects in a bucket whose key starts with the given prefix
+// A page contains at most 1000 objects
+func (s3handler *S3Handler) ListObjectPages(bucket string, prefix string, handlePage func(objects []types.Object) error) error {
+	paginator := s3.NewListObjectsV2Paginator(s3handler.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		if len(page.Contents) == 0 {
+			continue
+		}
+
+		err = handlePage(page.Contents)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteObjects Deletes the objects with the given keys from a bucket
+// At most 1000 keys can be deleted at once, keys of objects that do not exist are ignored
+func (s3handler *S3Handler) DeleteObjects(bucket string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var objectIdentifiers []types.ObjectIdentifier
+	for _, key := range keys {
+		objectIdentifiers = append(objectIdentifiers, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	output, err := s3handler.S3Client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{
+			Objects: objectIdentifiers,
+			Quiet:   true,
+		},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if len(output.Errors) != 0 {
+		err := fmt.Errorf("Could not delete %v objects: %v", len(output.Errors), aws.ToString(output.Errors[0].Message))
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// InitiateMultipartUpload Starts a new multipart upload for an object and returns its upload id
+func (s3handler *S3Handler) InitiateMultipartUpload(object *models.DatasetObjectEntry) (string, error) {
+	output, err := s3handler.S3Client.CreateMultipartUpload(context.Background(), &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(object.GetLocation().GetBucket()),
+		Key:    aws.String(object.GetLocation().GetKey()),
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	return aws.ToString(output.UploadId), nil
+}
+
+// MultipartUploadParts Splits an object of the given length into the parts of a multipart upload
+// The parts are returned in the order of their part numbers starting at 1, EndByte is exclusive
+// The configured part size is increased if the object would otherwise exceed the maximal number of parts
+func (s3handler *S3Handler) MultipartUploadParts(contentLen int64) []*models.IndexLocation {
+	partSize := s3handler.MultipartPartSize
+	if contentLen > partSize*maxMultipartParts {
+		partSize = (contentLen + maxMultipartParts - 1) / maxMultipartParts
+	}
+
+	if contentLen <= 0 {
+		return []*models.IndexLocation{{StartByte: 0, EndByte: 0}}
+	}
+
+	var parts []*models.IndexLocation
+	for start := int64(0); start < contentLen; start += partSize {
+		end := start + partSize
+		if end > contentLen {
+			end = contentLen
+		}
+
+		parts = append(parts, &models.IndexLocation{
+			StartByte: start,
+			EndByte:   end,
+		})
+	}
+
+	return parts
+}
+
+// CreatePresignedUploadPartLink Creates a new upload link for a single part of a multipart upload
+func (s3handler *S3Handler) CreatePresignedUploadPartLink(object *models.DatasetObjectEntry, uploadID string, partNumber int32) (string, error) {
+	presignedRequestURL, err := s3handler.PresignClient.PresignPutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(object.GetLocation().GetBucket()),
+		Key:    aws.String(object.GetLocation().GetKey()),
+	}, withUploadPartQuery(uploadID, partNumber))
+
+	if err != nil {
+		log.Println(err.Error())
+		return "", err
+	}
+
+	return presignedRequestURL.URL, nil
+}
+
+// CompleteMultipartUpload Completes a multipart upload from all uploaded parts
+// Fails if the size of the uploaded parts does not match the content length of the object
+func (s3handler *S3Handler) CompleteMultipartUpload(object *models.DatasetObjectEntry, uploadID string) error {
+	paginator := s3.NewListPartsPaginator(s3handler.S3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(object.GetLocation().GetBucket()),
+		Key:      aws.String(object.GetLocation().GetKey()),
+		UploadId: aws.String(uploadID),
+	})
+
+	var completedParts []types.CompletedPart
+	var uploadedSize int64
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		for _, part := range page.Parts {
+			completedParts = append(completedParts, types.CompletedPart{
+				ETag:       part.ETag,
+				PartNumber: part.PartNumber,
+			})
+			uploadedSize += part.Size
+		}
+	}
+
+	if uploadedSize != object.GetContentLen() {
+		err := apierrors.New(apierrors.FailedPrecondition, "Uploaded parts of object %v have a size of %v bytes, expected %v bytes", object.GetID(), uploadedSize, object.GetContentLen())
+		log.Println(err.Error())
+		return err
+	}
+
+	_, err := s3handler.S3Client.CompleteMultipartUpload(context.Background(), &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(object.GetLocation().GetBucket()),
+		Key:      aws.String(object.GetLocation().GetKey()),
+		UploadId: aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload Aborts a multipart upload and removes its uploaded parts
+func (s3handler *S3Handler) AbortMultipartUpload(object *models.DatasetObjectEntry, uploadID string) error {
+	return s3handler.AbortKeyMultipartUpload(object.GetLocation().GetBucket(), object.GetLocation().GetKey(), uploadID)
+}
+
+// ListMultipartUploadPages Calls handlePage for every page of multipart uploads in a bucket whose key starts with the given prefix
+// and that were neither completed nor aborted
+// A page contains at most 1000 multipart uploads
+func (s3handler *S3Handler) ListMultipartUploadPages(bucket string, prefix string, handlePage func(uploads []types.MultipartUpload) error) error {
+	input := s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+
+	for {
+		page, err := s3handler.S3Client.ListMultipartUploads(context.Background(), &input)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		if len(page.Uploads) != 0 {
+			err = handlePage(page.Uploads)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+		}
+
+		if !page.IsTruncated {
+			return nil
+		}
+
+		input.KeyMarker = page.NextKeyMarker
+		input.UploadIdMarker = page.NextUploadIdMarker
+	}
+}
+
+// AbortKeyMultipartUpload Aborts the multipart upload of a key in a bucket and removes its uploaded parts
+func (s3handler *S3Handler) AbortKeyMultipartUpload(bucket string, key string, uploadID string) error {
+	_, err := s3handler.S3Client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// withUploadPartQuery Turns a presigned PutObject request into an UploadPart request
+// The used version of the sdk does not support presigning UploadPart requests directly,
+// so the part parameters are added to the query before the request is signed
+func withUploadPartQuery(uploadID string, partNumber int32) func(*s3.PresignOptions) {
+	addUploadPartQuery := middleware.BuildMiddlewareFunc("AddUploadPartQuery", func(
+		ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+	) (middleware.BuildOutput, middleware.Metadata, error) {
+		request, ok := in.Request.(*smithyhttp.Request)
+		if !ok {
+			return middleware.BuildOutput{}, middleware.Metadata{}, fmt.Errorf("unexpected request type %T", in.Request)
+		}
+
+		query := request.URL.Query()
+		query.Set("partNumber", strconv.Itoa(int(partNumber)))
+		query.Set("uploadId", uploadID)
+		request.URL.RawQuery = query.Encode()
+
+		return next.HandleBuild(ctx, in)
+	})
+
+	return func(options *s3.PresignOptions) {
+		options.ClientOptions = append(options.ClientOptions, s3.WithAPIOptions(func(stack *middleware.Stack) error {
+			return stack.Build.Add(addUploadPartQuery, middleware.After)
+		}))
+	}
+}
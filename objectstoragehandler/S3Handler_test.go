@@ -83,3 +83,151 @@ func TestS3Handler_CreatePresignedLinks(t *testing.T) {
 	}
 
 }
+
+func TestS3Handler_MultipartUploadParts(t *testing.T) {
+	handler := S3Handler{
+		MultipartPartSize: minMultipartPartSize,
+	}
+
+	parts := handler.MultipartUploadParts(2*minMultipartPartSize + 1)
+	if len(parts) != 3 {
+		t.Fatalf("Expected 3 parts, got %v", len(parts))
+	}
+
+	if parts[2].GetStartByte() != 2*minMultipartPartSize || parts[2].GetEndByte() != 2*minMultipartPartSize+1 {
+		t.Errorf("Unexpected range of last part: %v", parts[2])
+	}
+
+	largeParts := handler.MultipartUploadParts(minMultipartPartSize * maxMultipartParts * 2)
+	if len(largeParts) != maxMultipartParts {
+		t.Errorf("Expected %v parts, got %v", maxMultipartParts, len(largeParts))
+	}
+}
+
+func TestS3Handler_MultipartUpload(t *testing.T) {
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "minioadmin")
+	os.Setenv("AWS_ACCESS_KEY_ID", "minioadmin")
+
+	data := bytes.Repeat([]byte("a"), minMultipartPartSize+1)
+
+	object := models.DatasetObjectEntry{
+		ID:         "multipart",
+		Filename:   "multipart",
+		Filetype:   "txt",
+		ContentLen: int64(len(data)),
+		Location: &models.Location{
+			Bucket:       "testbucket",
+			Key:          path.Join("foo", "multipart"),
+			LocationType: models.LocationType_Object,
+		},
+	}
+
+	handler, err := NewS3Handler()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	handler.MultipartPartSize = minMultipartPartSize
+
+	uploadID, err := handler.InitiateMultipartUpload(&object)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	for i, part := range handler.MultipartUploadParts(object.GetContentLen()) {
+		link, err := handler.CreatePresignedUploadPartLink(&object, uploadID, int32(i+1))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		req, err := http.NewRequest(http.MethodPut, link, bytes.NewBuffer(data[part.GetStartByte():part.GetEndByte()]))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		if resp.StatusCode != 200 {
+			t.Fatalf("%v", resp)
+		}
+	}
+
+	err = handler.CompleteMultipartUpload(&object, uploadID)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	downloadLink, err := handler.CreatePresignedDownloadLink(&object)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	downloadResp, err := http.Get(downloadLink)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	respData, err := ioutil.ReadAll(downloadResp.Body)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !bytes.Equal(data, respData) {
+		t.Fatalf("Downloaded data of multipart upload does not match the uploaded data")
+	}
+}
+
+func TestS3Handler_GetObjectSize(t *testing.T) {
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "minioadmin")
+	os.Setenv("AWS_ACCESS_KEY_ID", "minioadmin")
+
+	handler, err := NewS3Handler()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	object := models.DatasetObjectEntry{
+		ID: "sizetest",
+		Location: &models.Location{
+			Bucket:       "testbucket",
+			Key:          path.Join("foo", "sizetest"),
+			LocationType: models.LocationType_Object,
+		},
+	}
+
+	_, exists, err := handler.GetObjectSize(&object)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if exists {
+		t.Fatalf("Object that was never uploaded exists")
+	}
+
+	uploadLink, err := handler.CreatePresignedUploadLink(&object)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	req, err := http.NewRequest(http.MethodPut, uploadLink, bytes.NewBuffer([]byte("data")))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	_, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	size, exists, err := handler.GetObjectSize(&object)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if !exists || size != 4 {
+		t.Fatalf("Unexpected object size %v, exists: %v", size, exists)
+	}
+}
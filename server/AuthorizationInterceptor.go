@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/authhandler"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"google.golang.org/grpc"
+)
+
+//accessLevel Describes which kind of authorization a method requires
+type accessLevel int
+
+const (
+	//resourceAccess Requires a right on the resource referenced by the request
+	resourceAccess accessLevel = iota
+	//authenticatedAccess Requires an authenticated user but no right on a specific resource
+	authenticatedAccess
+	//userAccess Requires a user authenticated with an OAuth2 token, API tokens are rejected
+	userAccess
+	//publicAccess Requires no authentication at all
+	publicAccess
+)
+
+//resourceIDExtractor Returns the id of the resource a request is authorized against
+type resourceIDExtractor func(request interface{}) (string, error)
+
+//authorizationRule Declares how the requests to a single method are authorized
+type authorizationRule struct {
+	Access     accessLevel
+	Resource   models.Resource
+	Right      models.Right
+	ResourceID resourceIDExtractor
+}
+
+//requireRight Creates a rule that requires a right on the resource whose id is extracted from the request
+func requireRight(resource models.Resource, right models.Right, resourceID resourceIDExtractor) authorizationRule {
+	return authorizationRule{
+		Access:     resourceAccess,
+		Resource:   resource,
+		Right:      right,
+		ResourceID: resourceID,
+	}
+}
+
+//requireAuthentication Creates a rule that only requires an authenticated user
+func requireAuthentication() authorizationRule {
+	return authorizationRule{
+		Access: authenticatedAccess,
+	}
+}
+
+//requireUserAuthentication Creates a rule that requires a user authenticated with an OAuth2 token
+func requireUserAuthentication() authorizationRule {
+	return authorizationRule{
+		Access: userAccess,
+	}
+}
+
+//allowPublic Creates a rule that does not require any authentication
+func allowPublic() authorizationRule {
+	return authorizationRule{
+		Access: publicAccess,
+	}
+}
+
+//authorizationRules The authorization rules of all methods served by the server, indexed by the full gRPC method name
+//Every registered method requires a rule, the server does not start if a rule is missing
+func (endpoints *GenericEndpoints) authorizationRules() map[string]authorizationRule {
+	return map[string]authorizationRule{
+		"/ProjectAPI/CreateProject":      requireAuthentication(),
+		"/ProjectAPI/GetUserProjects":    requireAuthentication(),
+		"/ProjectAPI/AddUserToProject":   requireRight(models.Resource_Project, models.Right_Write, projectIDOfRequest),
+		"/ProjectAPI/GetProjectDatasets": requireRight(models.Resource_Project, models.Right_Read, idOfRequest),
+		"/ProjectAPI/DeleteProject":      requireRight(models.Resource_Project, models.Right_Write, idOfRequest),
+
+		// The searched projects are reduced to the readable projects by the endpoint
+		"/ProjectSearchService/FullTextSearch": requireAuthentication(),
+
+		"/ProjectUserService/RemoveUserFromProject": requireRight(models.Resource_Project, models.Right_Write, projectIDOfRequest),
+		"/ProjectUserService/ChangeUserRights":      requireRight(models.Resource_Project, models.Right_Write, projectIDOfRequest),
+
+		"/DatasetService/CreateNewDataset":           requireRight(models.Resource_Project, models.Right_Write, projectIDOfRequest),
+		"/DatasetService/Dataset":                    requireRight(models.Resource_Dataset, models.Right_Read, idOfRequest),
+		"/DatasetService/DatasetVersions":            requireRight(models.Resource_Dataset, models.Right_Read, idOfRequest),
+		"/DatasetService/DatasetObjectGroups":        requireRight(models.Resource_Dataset, models.Right_Read, idOfRequest),
+		"/DatasetService/UpdateDatasetField":         requireRight(models.Resource_Dataset, models.Right_Write, idOfRequest),
+		"/DatasetService/DeleteDataset":              requireRight(models.Resource_Dataset, models.Right_Write, idOfRequest),
+		"/DatasetService/ReleaseDatasetVersion":      requireRight(models.Resource_Dataset, models.Right_Write, datasetIDOfRequest),
+		"/DatasetService/DatasetVersionObjectGroups": requireRight(models.Resource_DatasetVersion, models.Right_Read, idOfRequest),
+
+		"/ObjectGroupStreamService/StreamDatasetObjectGroups":        requireRight(models.Resource_Dataset, models.Right_Read, idOfRequest),
+		"/ObjectGroupStreamService/StreamDatasetVersionObjectGroups": requireRight(models.Resource_DatasetVersion, models.Right_Read, idOfRequest),
+
+		"/DatasetObjectsService/CreateObjectHeritage": requireRight(models.Resource_Dataset, models.Right_Write, datasetIDOfRequest),
+		"/DatasetObjectsService/CreateObjectGroup":    requireRight(models.Resource_Dataset, models.Right_Write, datasetIDOfRequest),
+		"/DatasetObjectsService/GetObjectGroup":       requireRight(models.Resource_DatasetObjectGroupResource, models.Right_Read, idOfRequest),
+		"/DatasetObjectsService/FinishObjectUpload":   requireRight(models.Resource_DatasetObjectGroupResource, models.Right_Write, idOfRequest),
+
+		// The search is either scoped to a project or to a dataset, the endpoint checks the read right on the requested scope
+		"/ObjectGroupSearchService/SearchObjectGroups": requireAuthentication(),
+
+		"/ObjectHeritageService/GetObjectHeritage":             requireRight(models.Resource_Dataset, models.Right_Read, endpoints.objectHeritageDatasetID),
+		"/ObjectHeritageService/GetObjectHeritageObjectGroups": requireRight(models.Resource_Dataset, models.Right_Read, endpoints.objectHeritageDatasetID),
+		"/ObjectHeritageService/GetRelatedObjectGroups":        requireRight(models.Resource_DatasetObjectGroupResource, models.Right_Read, idOfRequest),
+
+		"/ObjectLoad/CreateUploadLink":   requireRight(models.Resource_DatasetObject, models.Right_Write, idOfRequest),
+		"/ObjectLoad/CreateDownloadLink": requireRight(models.Resource_DatasetObject, models.Right_Read, idOfRequest),
+
+		"/MultipartUploadService/InitiateMultipartUpload":    requireRight(models.Resource_DatasetObject, models.Right_Write, idOfRequest),
+		"/MultipartUploadService/CreateMultipartUploadLinks": requireRight(models.Resource_DatasetObject, models.Right_Write, idOfRequest),
+		"/MultipartUploadService/CompleteMultipartUpload":    requireRight(models.Resource_DatasetObject, models.Right_Write, idOfRequest),
+		"/MultipartUploadService/AbortMultipartUpload":       requireRight(models.Resource_DatasetObject, models.Right_Write, idOfRequest),
+
+		// The rights requested for a new token are checked by the endpoint, the other methods only act on tokens of the user
+		// API tokens can not manage tokens, otherwise a leaked token could be used to create further tokens
+		"/TokenService/CreateToken":   requireUserAuthentication(),
+		"/TokenService/GetUserTokens": requireUserAuthentication(),
+		"/TokenService/RevokeToken":   requireUserAuthentication(),
+
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": allowPublic(),
+	}
+}
+
+//AuthorizationInterceptor Authorizes every request against the authorization rule of the called method
+type AuthorizationInterceptor struct {
+	AuthHandler authhandler.AuthHandler
+	Rules       map[string]authorizationRule
+}
+
+//NewAuthorizationInterceptor Creates a new interceptor with the authorization rules of all endpoints
+func NewAuthorizationInterceptor(genericEndpoints *GenericEndpoints) *AuthorizationInterceptor {
+	return &AuthorizationInterceptor{
+		AuthHandler: genericEndpoints.AuthHandler,
+		Rules:       genericEndpoints.authorizationRules(),
+	}
+}
+
+//CheckRules Returns an error if a method of the given services has no authorization rule
+func (interceptor *AuthorizationInterceptor) CheckRules(serviceInfo map[string]grpc.ServiceInfo) error {
+	var missingRules []string
+
+	for serviceName, info := range serviceInfo {
+		for _, method := range info.Methods {
+			fullMethod := fmt.Sprintf("/%v/%v", serviceName, method.Name)
+			if _, ok := interceptor.Rules[fullMethod]; !ok {
+				missingRules = append(missingRules, fullMethod)
+			}
+		}
+	}
+
+	if len(missingRules) != 0 {
+		sort.Strings(missingRules)
+		return fmt.Errorf("Missing authorization rules for methods: %v", missingRules)
+	}
+
+	return nil
+}
+
+//Unary Authorizes unary requests before they are passed to the endpoint
+func (interceptor *AuthorizationInterceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	err := interceptor.authorize(ctx, info.FullMethod, req)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+//Stream Authorizes streaming requests
+//Rules that require a right on a resource are checked once the first request message is received
+func (interceptor *AuthorizationInterceptor) Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	rule, err := interceptor.rule(info.FullMethod)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	if rule.Access != resourceAccess {
+		err := interceptor.authorize(stream.Context(), info.FullMethod, nil)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+
+	return handler(srv, &authorizingServerStream{
+		ServerStream: stream,
+		authorize: func(request interface{}) error {
+			return interceptor.authorize(stream.Context(), info.FullMethod, request)
+		},
+	})
+}
+
+func (interceptor *AuthorizationInterceptor) rule(fullMethod string) (authorizationRule, error) {
+	rule, ok := interceptor.Rules[fullMethod]
+	if !ok {
+		return authorizationRule{}, apierrors.New(apierrors.PermissionDenied, "No authorization rule for method %v", fullMethod)
+	}
+
+	return rule, nil
+}
+
+//authorize Checks a request against the authorization rule of the called method
+func (interceptor *AuthorizationInterceptor) authorize(ctx context.Context, fullMethod string, request interface{}) error {
+	rule, err := interceptor.rule(fullMethod)
+	if err != nil {
+		return err
+	}
+
+	switch rule.Access {
+	case publicAccess:
+		return nil
+	case authenticatedAccess:
+		_, err := interceptor.AuthHandler.UserID(ctx)
+		return err
+	case userAccess:
+		tokenType, err := interceptor.AuthHandler.TokenType(ctx)
+		if err != nil {
+			return err
+		}
+
+		if tokenType != authhandler.OAuth2Token {
+			return apierrors.New(apierrors.PermissionDenied, "Method %v can not be called with an API token", fullMethod)
+		}
+
+		_, err = interceptor.AuthHandler.UserID(ctx)
+		return err
+	}
+
+	resourceID, err := rule.ResourceID(request)
+	if err != nil {
+		return err
+	}
+
+	authorized, err := interceptor.AuthHandler.Authorize(ctx, rule.Resource, rule.Right, resourceID)
+	if err != nil {
+		return err
+	}
+
+	if !authorized {
+		return apierrors.NewPermissionDenied(rule.Right, rule.Resource, resourceID)
+	}
+
+	return nil
+}
+
+//authorizingServerStream Authorizes the first message received from a stream before it is passed to the endpoint
+type authorizingServerStream struct {
+	grpc.ServerStream
+	authorize  func(request interface{}) error
+	authorized bool
+}
+
+func (stream *authorizingServerStream) RecvMsg(m interface{}) error {
+	err := stream.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+
+	if !stream.authorized {
+		err = stream.authorize(m)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		stream.authorized = true
+	}
+
+	return nil
+}
+
+//idOfRequest Extracts the ID field of a request
+func idOfRequest(request interface{}) (string, error) {
+	if idRequest, ok := request.(interface{ GetID() string }); ok {
+		return idRequest.GetID(), nil
+	}
+
+	return "", unexpectedRequestType(request)
+}
+
+//projectIDOfRequest Extracts the ProjectID field of a request
+func projectIDOfRequest(request interface{}) (string, error) {
+	if projectRequest, ok := request.(interface{ GetProjectID() string }); ok {
+		return projectRequest.GetProjectID(), nil
+	}
+
+	return "", unexpectedRequestType(request)
+}
+
+//datasetIDOfRequest Extracts the DatasetID field of a request
+func datasetIDOfRequest(request interface{}) (string, error) {
+	if datasetRequest, ok := request.(interface{ GetDatasetID() string }); ok {
+		return datasetRequest.GetDatasetID(), nil
+	}
+
+	return "", unexpectedRequestType(request)
+}
+
+//objectHeritageDatasetID Returns the id of the dataset of the object heritage referenced by the ID field of a request
+func (endpoints *GenericEndpoints) objectHeritageDatasetID(request interface{}) (string, error) {
+	objectHeritageID, err := idOfRequest(request)
+	if err != nil {
+		return "", err
+	}
+
+	objectHeritage, err := endpoints.ObjectHeritageHandler.GetObjectHeritage(objectHeritageID)
+	if err != nil {
+		return "", err
+	}
+
+	return objectHeritage.GetDatasetID(), nil
+}
+
+func unexpectedRequestType(request interface{}) error {
+	return fmt.Errorf("Can not extract resource id from request of type %T", request)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/authhandler"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+type testAuthHandler struct {
+	authorizedIDs map[string]bool
+	tokenType     authhandler.TokenType
+}
+
+func (handler *testAuthHandler) Authorize(requestContext context.Context, resource models.Resource, requiredRight models.Right, resourceID string) (bool, error) {
+	return handler.authorizedIDs[resourceID], nil
+}
+
+func (handler *testAuthHandler) UserID(requestContext context.Context) (string, error) {
+	return "testuser", nil
+}
+
+func (handler *testAuthHandler) TokenType(requestContext context.Context) (authhandler.TokenType, error) {
+	return handler.tokenType, nil
+}
+
+func TestAuthorizationInterceptor_CheckRules(t *testing.T) {
+	genericEndpoints := &GenericEndpoints{}
+	interceptor := NewAuthorizationInterceptor(genericEndpoints)
+
+	grpcServer := grpc.NewServer()
+	services.RegisterProjectAPIServer(grpcServer, &ProjectEndpoints{GenericEndpoints: genericEndpoints})
+	services.RegisterDatasetServiceServer(grpcServer, &DatasetEndpoints{GenericEndpoints: genericEndpoints})
+	services.RegisterDatasetObjectsServiceServer(grpcServer, &ObjectEndpoints{GenericEndpoints: genericEndpoints})
+	services.RegisterObjectLoadServer(grpcServer, &LoadEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&projectUserServiceDesc, &ProjectEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&objectHeritageServiceDesc, &ObjectEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&multipartUploadServiceDesc, &LoadEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&tokenServiceDesc, &TokenEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&objectGroupStreamServiceDesc, &DatasetEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&objectGroupSearchServiceDesc, &ObjectEndpoints{GenericEndpoints: genericEndpoints})
+	grpcServer.RegisterService(&projectSearchServiceDesc, &ProjectEndpoints{GenericEndpoints: genericEndpoints})
+	reflection.Register(grpcServer)
+
+	err := interceptor.CheckRules(grpcServer.GetServiceInfo())
+	if err != nil {
+		t.Error(err)
+	}
+
+	delete(interceptor.Rules, "/DatasetService/Dataset")
+
+	err = interceptor.CheckRules(grpcServer.GetServiceInfo())
+	if err == nil {
+		t.Errorf("Missing authorization rule was not detected")
+	}
+}
+
+func TestAuthorizationInterceptor_Unary(t *testing.T) {
+	interceptor := NewAuthorizationInterceptor(&GenericEndpoints{
+		AuthHandler: &testAuthHandler{
+			authorizedIDs: map[string]bool{"authorized": true},
+		},
+	})
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &models.Empty{}, nil
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/DatasetService/Dataset"}
+
+	_, err := interceptor.Unary(context.Background(), &models.ID{ID: "authorized"}, info, handler)
+	if err != nil {
+		t.Errorf("Authorized request was rejected: %v", err)
+	}
+
+	_, err = interceptor.Unary(context.Background(), &models.ID{ID: "unauthorized"}, info, handler)
+	if !apierrors.Is(err, apierrors.PermissionDenied) {
+		t.Errorf("Expected permission denied error for unauthorized request, got: %v", err)
+	}
+
+	_, err = interceptor.Unary(context.Background(), &models.ID{ID: "authorized"}, &grpc.UnaryServerInfo{FullMethod: "/Unknown/Method"}, handler)
+	if !apierrors.Is(err, apierrors.PermissionDenied) {
+		t.Errorf("Expected permission denied error for method without rule, got: %v", err)
+	}
+}
+
+func TestAuthorizationInterceptor_TokenService(t *testing.T) {
+	authHandler := &testAuthHandler{}
+	interceptor := NewAuthorizationInterceptor(&GenericEndpoints{
+		AuthHandler: authHandler,
+	})
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &models.Empty{}, nil
+	}
+
+	for _, method := range []string{"/TokenService/CreateToken", "/TokenService/GetUserTokens", "/TokenService/RevokeToken"} {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+
+		authHandler.tokenType = authhandler.OAuth2Token
+		_, err := interceptor.Unary(context.Background(), &models.Empty{}, info, handler)
+		if err != nil {
+			t.Errorf("Request to %v with OAuth2 token was rejected: %v", method, err)
+		}
+
+		authHandler.tokenType = authhandler.UserAPIToken
+		_, err = interceptor.Unary(context.Background(), &models.Empty{}, info, handler)
+		if !apierrors.Is(err, apierrors.PermissionDenied) {
+			t.Errorf("Expected permission denied error for request to %v with API token, got: %v", method, err)
+		}
+	}
+}
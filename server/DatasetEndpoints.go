@@ -2,10 +2,11 @@ package server
 
 import (
 	"context"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
 )
@@ -25,44 +26,33 @@ func NewDatasetEndpoints(genericEndpoints *GenericEndpoints) (*DatasetEndpoints,
 }
 
 // CreateNewDataset Creates a new dataset and associates it with a dataset
+// A retried request with the same IdempotencyKey metadata returns the dataset that was created by the first request
 func (datasetEndpoint *DatasetEndpoints) CreateNewDataset(ctx context.Context, request *services.CreateDatasetRequest) (*models.DatasetEntry, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_Project, models.Right_Write, request.ProjectID)
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
+	var entry *models.DatasetEntry
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_Project, request.ProjectID)
-		log.Println(err.Error())
-		return nil, err
-
-	}
+	datasetID, created, err := datasetEndpoint.createIdempotent(ctx, models.Resource_Dataset, request, func(handler *databasehandler.DBUtilsHandler) (string, error) {
+		datasetHandler := databasehandler.DatasetActionHandler{
+			DBUtilsHandler: handler,
+		}
 
-	entry, err := datasetEndpoint.DatasetHandler.CreateNewDataset(request)
+		var err error
+		entry, err = datasetHandler.CreateNewDataset(request)
+		return entry.GetID(), err
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
+	if !created {
+		return datasetEndpoint.DatasetHandler.GetDataset(datasetID)
+	}
+
 	return entry, nil
 }
 
 // Dataset Returns a specific dataset
 func (datasetEndpoint *DatasetEndpoints) Dataset(ctx context.Context, id *models.ID) (*models.DatasetEntry, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_Dataset, models.Right_Read, id.GetID())
-	if err != nil {
-		log.Println(err.Error())
-		return nil, err
-	}
-
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_Dataset, id.GetID())
-		log.Println(err.Error())
-		return nil, err
-
-	}
-
 	entry, err := datasetEndpoint.DatasetHandler.GetDataset(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
@@ -72,22 +62,22 @@ func (datasetEndpoint *DatasetEndpoints) Dataset(ctx context.Context, id *models
 	return entry, nil
 }
 
-//DatasetVersions Lists Versions of a dataset
+//DatasetVersions Lists the versions of a dataset
+//Only a page is returned if the PageSize or PageToken metadata is sent, the token of the next page is returned in the NextPageToken header
 func (datasetEndpoint *DatasetEndpoints) DatasetVersions(ctx context.Context, id *models.ID) (*services.DatasetVersionList, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_Dataset, models.Right_Read, id.GetID())
+	page, err := pageRequest(ctx)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Read, models.Resource_Dataset, id.GetID())
+	entries, nextPageToken, err := datasetEndpoint.DatasetHandler.GetDatasetVersions(id.GetID(), page)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
-
 	}
 
-	entries, err := datasetEndpoint.DatasetHandler.GetDatasetVersions(id.GetID())
+	err = setNextPageToken(ctx, nextPageToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -100,31 +90,58 @@ func (datasetEndpoint *DatasetEndpoints) DatasetVersions(ctx context.Context, id
 	return &versionList, nil
 }
 
-//UpdateDatasetField Updates a field of a dataset
-func (datasetEndpoint *DatasetEndpoints) UpdateDatasetField(_ context.Context, _ *models.UpdateFieldsRequest) (*models.DatasetEntry, error) {
-	panic("not implemented") // TODO: Implement
-}
+//UpdateDatasetField Updates fields of a dataset
+//Supported fields are Datasetname, Datasettype, Description, IsPublic and Labels, which is given as JSON array of objects with a Key and a Value
+func (datasetEndpoint *DatasetEndpoints) UpdateDatasetField(ctx context.Context, request *models.UpdateFieldsRequest) (*models.DatasetEntry, error) {
+	entry, err := datasetEndpoint.DatasetHandler.UpdateDatasetFields(request.GetID(), request.GetUpdateStringFields())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
 
-// DeleteDataset Delete a dataset
-func (datasetEndpoint *DatasetEndpoints) DeleteDataset(_ context.Context, _ *models.ID) (*models.Empty, error) {
-	panic("not implemented") // TODO: Implement
+	return entry, nil
 }
 
-//ReleaseDatasetVersion Release a new dataset version
-func (datasetEndpoint *DatasetEndpoints) ReleaseDatasetVersion(ctx context.Context, request *services.ReleaseDatasetVersionRequest) (*models.DatasetVersionEntry, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_Dataset, models.Right_Write, request.GetDatasetID())
+// DeleteDataset Delete a dataset
+// Datasets with associated dataset versions or object groups are only deleted if the CascadeMetadataKey metadata is "true"
+// In that case the versions, object groups and their objects in the object storage are deleted as well
+func (datasetEndpoint *DatasetEndpoints) DeleteDataset(ctx context.Context, id *models.ID) (*models.Empty, error) {
+	dataset, err := datasetEndpoint.DatasetHandler.GetDataset(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_Dataset, request.GetDatasetID())
+	cascade := cascadeRequested(ctx)
+
+	if !cascade {
+		err = datasetEndpoint.DatasetHandler.CheckDatasetIsEmpty(dataset.GetID())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+	}
+
+	err = datasetEndpoint.ObjectStorageHandler.DeleteObjectsWithPrefix(
+		datasetEndpoint.ObjectGroupHandler.BucketName,
+		databasehandler.ObjectKeyPrefix(dataset.GetProjectID(), dataset.GetID()),
+	)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
+	}
 
+	err = datasetEndpoint.DatasetHandler.DeleteDataset(dataset.GetID(), cascade)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
+	return &models.Empty{}, nil
+}
+
+//ReleaseDatasetVersion Release a new dataset version
+func (datasetEndpoint *DatasetEndpoints) ReleaseDatasetVersion(ctx context.Context, request *services.ReleaseDatasetVersionRequest) (*models.DatasetVersionEntry, error) {
 	version, err := datasetEndpoint.DatasetVersionHandler.ReleaseDatasetVersion(request)
 	if err != nil {
 		log.Println(err.Error())
@@ -134,27 +151,28 @@ func (datasetEndpoint *DatasetEndpoints) ReleaseDatasetVersion(ctx context.Conte
 	return version, nil
 }
 
+//DatasetVersionObjectGroups Lists the object groups of a dataset version
+//Only a page is returned if the PageSize or PageToken metadata is sent, the token of the next page is returned in the NextPageToken header
 func (datasetEndpoint *DatasetEndpoints) DatasetVersionObjectGroups(ctx context.Context, request *models.ID) (*services.ObjectGroupList, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_DatasetVersion, models.Right_Write, request.GetID())
+	page, err := pageRequest(ctx)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_DatasetVersion, request.GetID())
+	version, err := datasetEndpoint.DatasetVersionHandler.GetDatasetVersion(request.ID)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
-
 	}
 
-	version, err := datasetEndpoint.DatasetVersionHandler.GetDatasetVersion(request.ID)
+	groups, nextPageToken, err := datasetEndpoint.ObjectGroupHandler.GetObjectGroups(version.GetObjectIDs(), page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	groups, err := datasetEndpoint.ObjectGroupHandler.GetObjectGroups(version.GetObjectIDs())
+	err = setNextPageToken(ctx, nextPageToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -167,21 +185,22 @@ func (datasetEndpoint *DatasetEndpoints) DatasetVersionObjectGroups(ctx context.
 	return &objectGroupList, nil
 }
 
-//DatasetObjectGroups Lists all objects of a dataset
+//DatasetObjectGroups Lists the object groups of a dataset
+//Only a page is returned if the PageSize or PageToken metadata is sent, the token of the next page is returned in the NextPageToken header
 func (datasetEndpoint *DatasetEndpoints) DatasetObjectGroups(ctx context.Context, request *models.ID) (*services.ObjectGroupList, error) {
-	authorized, err := datasetEndpoint.AuthHandler.Authorize(ctx, models.Resource_Dataset, models.Right_Read, request.GetID())
+	page, err := pageRequest(ctx)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Read, models.Resource_Dataset, request.GetID())
+	groups, nextPageToken, err := datasetEndpoint.ObjectGroupHandler.GetDatasetObjects(request.GetID(), page)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	groups, err := datasetEndpoint.ObjectGroupHandler.GetDatasetObjects(request.GetID())
+	err = setNextPageToken(ctx, nextPageToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -193,3 +212,51 @@ func (datasetEndpoint *DatasetEndpoints) DatasetObjectGroups(ctx context.Context
 
 	return &objectGroupList, nil
 }
+
+//StreamDatasetObjectGroups Streams all object groups of a dataset
+//The object groups are sent while they are read from the database, the stream ends after the last object group is sent
+//or when the client cancels it
+func (datasetEndpoint *DatasetEndpoints) StreamDatasetObjectGroups(request *models.ID, stream ObjectGroupStream) error {
+	err := datasetEndpoint.ObjectGroupHandler.StreamDatasetObjects(stream.Context(), request.GetID(), stream.Send)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//StreamDatasetVersionObjectGroups Streams all object groups of a dataset version
+//The object groups are sent while they are read from the database, the stream ends after the last object group is sent
+//or when the client cancels it
+func (datasetEndpoint *DatasetEndpoints) StreamDatasetVersionObjectGroups(request *models.ID, stream ObjectGroupStream) error {
+	version, err := datasetEndpoint.DatasetVersionHandler.GetDatasetVersion(request.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	err = datasetEndpoint.ObjectGroupHandler.StreamObjectGroups(stream.Context(), version.GetObjectIDs(), stream.Send)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//CascadeMetadataKey Request metadata key of dataset delete requests
+//If it is "true" the dataset is deleted together with its versions, object groups and object heritages, otherwise only empty datasets are deleted
+const CascadeMetadataKey = "Cascade"
+
+//cascadeRequested Checks whether the request metadata asks for a cascading delete
+func cascadeRequested(ctx context.Context) bool {
+	meta, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	cascade := meta.Get(CascadeMetadataKey)
+
+	return len(cascade) > 0 && cascade[0] == "true"
+}
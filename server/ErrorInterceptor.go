@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//errorDomain Domain of the error details attached to returned status errors
+const errorDomain = "ScienceObjectsDB"
+
+//errorKindCodes Maps the kinds of API errors to gRPC status codes
+var errorKindCodes = map[apierrors.Kind]codes.Code{
+	apierrors.Internal:           codes.Internal,
+	apierrors.NotFound:           codes.NotFound,
+	apierrors.PermissionDenied:   codes.PermissionDenied,
+	apierrors.Unauthenticated:    codes.Unauthenticated,
+	apierrors.AlreadyExists:      codes.AlreadyExists,
+	apierrors.FailedPrecondition: codes.FailedPrecondition,
+	apierrors.InvalidArgument:    codes.InvalidArgument,
+}
+
+//errorUnaryInterceptor Translates errors returned by unary endpoints into gRPC status errors
+func errorUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatusError(err)
+}
+
+//errorStreamInterceptor Translates errors returned by streaming endpoints into gRPC status errors
+func errorStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, stream))
+}
+
+//toStatusError Converts an error into a gRPC status error
+//API errors are mapped to the status code of their kind and carry their kind and affected resource as details
+//Errors that already are status errors are returned unchanged, all other errors are reported as internal errors
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var apiError *apierrors.Error
+	if !errors.As(err, &apiError) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	errorStatus := status.New(kindCode(apiError.Kind), apiError.Error())
+
+	errorInfo := &errdetails.ErrorInfo{
+		Reason: apiError.Kind.String(),
+		Domain: errorDomain,
+	}
+
+	detailedStatus, err := errorStatus.WithDetails(errorInfo)
+	if apiError.ResourceType != "" {
+		detailedStatus, err = errorStatus.WithDetails(errorInfo, &errdetails.ResourceInfo{
+			ResourceType: apiError.ResourceType,
+			ResourceName: apiError.ResourceID,
+			Description:  apiError.Error(),
+		})
+	}
+
+	if err != nil {
+		log.Println(err.Error())
+		return errorStatus.Err()
+	}
+
+	return detailedStatus.Err()
+}
+
+//kindCode Returns the gRPC status code of an error kind, kinds without a mapped code are internal errors
+func kindCode(kind apierrors.Kind) codes.Code {
+	code, ok := errorKindCodes[kind]
+	if !ok {
+		return codes.Internal
+	}
+
+	return code
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		code         codes.Code
+		reason       string
+		resourceType string
+		resourceID   string
+	}{
+		{name: "internal", err: apierrors.New(apierrors.Internal, "internal"), code: codes.Internal, reason: "INTERNAL"},
+		{name: "not found", err: apierrors.NewNotFound("Dataset", "1"), code: codes.NotFound, reason: "NOT_FOUND", resourceType: "Dataset", resourceID: "1"},
+		{name: "permission denied", err: apierrors.New(apierrors.PermissionDenied, "denied"), code: codes.PermissionDenied, reason: "PERMISSION_DENIED"},
+		{name: "unauthenticated", err: apierrors.New(apierrors.Unauthenticated, "unauthenticated"), code: codes.Unauthenticated, reason: "UNAUTHENTICATED"},
+		{name: "already exists", err: apierrors.New(apierrors.AlreadyExists, "exists"), code: codes.AlreadyExists, reason: "ALREADY_EXISTS"},
+		{name: "failed precondition", err: apierrors.New(apierrors.FailedPrecondition, "precondition"), code: codes.FailedPrecondition, reason: "FAILED_PRECONDITION"},
+		{name: "invalid argument", err: apierrors.New(apierrors.InvalidArgument, "invalid"), code: codes.InvalidArgument, reason: "INVALID_ARGUMENT"},
+		{name: "unknown kind", err: &apierrors.Error{Kind: apierrors.Kind(100), Message: "unknown"}, code: codes.Internal},
+		{name: "wrapped", err: fmt.Errorf("wrapped: %w", apierrors.NewNotFound("Project", "2")), code: codes.NotFound, reason: "NOT_FOUND", resourceType: "Project", resourceID: "2"},
+		{name: "canceled", err: context.Canceled, code: codes.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("query: %w", context.DeadlineExceeded), code: codes.DeadlineExceeded},
+		{name: "plain", err: errors.New("plain"), code: codes.Internal},
+		{name: "status", err: status.Error(codes.Unavailable, "unavailable"), code: codes.Unavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := toStatusError(test.err)
+			if err == nil {
+				t.Fatalf("Error was converted into a success")
+			}
+
+			errorStatus, ok := status.FromError(err)
+			if !ok {
+				t.Fatalf("Expected a status error, got: %v", err)
+			}
+
+			if errorStatus.Code() != test.code {
+				t.Errorf("Expected code %v, got %v", test.code, errorStatus.Code())
+			}
+
+			var errorInfo *errdetails.ErrorInfo
+			var resourceInfo *errdetails.ResourceInfo
+			for _, detail := range errorStatus.Details() {
+				switch detail := detail.(type) {
+				case *errdetails.ErrorInfo:
+					errorInfo = detail
+				case *errdetails.ResourceInfo:
+					resourceInfo = detail
+				}
+			}
+
+			if test.reason != "" && (errorInfo == nil || errorInfo.GetReason() != test.reason || errorInfo.GetDomain() != errorDomain) {
+				t.Errorf("Expected error info with reason %v, got: %v", test.reason, errorInfo)
+			}
+
+			if test.resourceType == "" && resourceInfo != nil {
+				t.Errorf("Unexpected resource info: %v", resourceInfo)
+			}
+
+			if test.resourceType != "" && (resourceInfo == nil || resourceInfo.GetResourceType() != test.resourceType || resourceInfo.GetResourceName() != test.resourceID) {
+				t.Errorf("Expected resource info for %v %v, got: %v", test.resourceType, test.resourceID, resourceInfo)
+			}
+		})
+	}
+
+	if toStatusError(nil) != nil {
+		t.Errorf("Success was converted into an error")
+	}
+}
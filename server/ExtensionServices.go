@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// This file contains the service definitions for RPCs that are not yet part of the go-api stubs.
+// They reuse the existing API messages and follow the layout of the generated code,
+// so they can be replaced by the generated definitions once they are added to the API.
+// The services and the fields of their Struct messages are documented in the README.
+
+//extensionServiceMetadata The proto file of the extension services, there is none until they are added to the API
+//Server reflection lists the extension services but can not describe them
+const extensionServiceMetadata = ""
+
+type unaryMethodCall func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error)
+
+//newUnaryHandler Creates a grpc method handler that decodes the request into the message returned by newRequest
+func newUnaryHandler(fullMethod string, newRequest func() interface{}, call unaryMethodCall) func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := newRequest()
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv, ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: fullMethod,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv, ctx, req)
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+//decodeStruct Decodes a request that is passed as a Struct into the target value using the JSON representation of the Struct
+//Fields that are unknown to the target are rejected
+func decodeStruct(request *structpb.Struct, target interface{}) error {
+	jsonRequest, err := request.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonRequest))
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(target)
+	if err != nil {
+		return apierrors.New(apierrors.InvalidArgument, "Invalid request: %v", err.Error())
+	}
+
+	return nil
+}
+
+//ProjectUserServiceServer Manages the members of a project
+type ProjectUserServiceServer interface {
+	RemoveUserFromProject(context.Context, *services.AddUserToProjectRequest) (*models.ProjectEntry, error)
+	ChangeUserRights(context.Context, *services.AddUserToProjectRequest) (*models.ProjectEntry, error)
+}
+
+func newAddUserToProjectRequest() interface{} {
+	return new(services.AddUserToProjectRequest)
+}
+
+var projectUserServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ProjectUserService",
+	HandlerType: (*ProjectUserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RemoveUserFromProject",
+			Handler: newUnaryHandler("/ProjectUserService/RemoveUserFromProject", newAddUserToProjectRequest,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ProjectUserServiceServer).RemoveUserFromProject(ctx, request.(*services.AddUserToProjectRequest))
+				}),
+		},
+		{
+			MethodName: "ChangeUserRights",
+			Handler: newUnaryHandler("/ProjectUserService/ChangeUserRights", newAddUserToProjectRequest,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ProjectUserServiceServer).ChangeUserRights(ctx, request.(*services.AddUserToProjectRequest))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: extensionServiceMetadata,
+}
+
+//ObjectHeritageServiceServer Queries object heritages and their object groups
+type ObjectHeritageServiceServer interface {
+	GetObjectHeritage(context.Context, *models.ID) (*models.ObjectHeritage, error)
+	GetObjectHeritageObjectGroups(context.Context, *models.ID) (*services.ObjectGroupList, error)
+	GetRelatedObjectGroups(context.Context, *models.ID) (*services.ObjectGroupList, error)
+}
+
+func newID() interface{} {
+	return new(models.ID)
+}
+
+var objectHeritageServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ObjectHeritageService",
+	HandlerType: (*ObjectHeritageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetObjectHeritage",
+			Handler: newUnaryHandler("/ObjectHeritageService/GetObjectHeritage", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ObjectHeritageServiceServer).GetObjectHeritage(ctx, request.(*models.ID))
+				}),
+		},
+		{
+			MethodName: "GetObjectHeritageObjectGroups",
+			Handler: newUnaryHandler("/ObjectHeritageService/GetObjectHeritageObjectGroups", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ObjectHeritageServiceServer).GetObjectHeritageObjectGroups(ctx, request.(*models.ID))
+				}),
+		},
+		{
+			MethodName: "GetRelatedObjectGroups",
+			Handler: newUnaryHandler("/ObjectHeritageService/GetRelatedObjectGroups", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ObjectHeritageServiceServer).GetRelatedObjectGroups(ctx, request.(*models.ID))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: extensionServiceMetadata,
+}
+
+//MultipartUploadServiceServer Handles multipart uploads of large objects
+type MultipartUploadServiceServer interface {
+	InitiateMultipartUpload(context.Context, *models.ID) (*models.DatasetObjectEntry, error)
+	CreateMultipartUploadLinks(*models.ID, MultipartUploadService_CreateMultipartUploadLinksServer) error
+	CompleteMultipartUpload(context.Context, *models.ID) (*models.Empty, error)
+	AbortMultipartUpload(context.Context, *models.ID) (*models.Empty, error)
+}
+
+//MultipartUploadService_CreateMultipartUploadLinksServer Server stream of the CreateMultipartUploadLinks RPC
+type MultipartUploadService_CreateMultipartUploadLinksServer interface {
+	Send(*services.CreateUploadLinkResponse) error
+	grpc.ServerStream
+}
+
+type multipartUploadServiceCreateMultipartUploadLinksServer struct {
+	grpc.ServerStream
+}
+
+func (x *multipartUploadServiceCreateMultipartUploadLinksServer) Send(m *services.CreateUploadLinkResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func multipartUploadServiceCreateMultipartUploadLinksHandler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(models.ID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MultipartUploadServiceServer).CreateMultipartUploadLinks(m, &multipartUploadServiceCreateMultipartUploadLinksServer{stream})
+}
+
+var multipartUploadServiceDesc = grpc.ServiceDesc{
+	ServiceName: "MultipartUploadService",
+	HandlerType: (*MultipartUploadServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InitiateMultipartUpload",
+			Handler: newUnaryHandler("/MultipartUploadService/InitiateMultipartUpload", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(MultipartUploadServiceServer).InitiateMultipartUpload(ctx, request.(*models.ID))
+				}),
+		},
+		{
+			MethodName: "CompleteMultipartUpload",
+			Handler: newUnaryHandler("/MultipartUploadService/CompleteMultipartUpload", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(MultipartUploadServiceServer).CompleteMultipartUpload(ctx, request.(*models.ID))
+				}),
+		},
+		{
+			MethodName: "AbortMultipartUpload",
+			Handler: newUnaryHandler("/MultipartUploadService/AbortMultipartUpload", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(MultipartUploadServiceServer).AbortMultipartUpload(ctx, request.(*models.ID))
+				}),
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CreateMultipartUploadLinks",
+			Handler:       multipartUploadServiceCreateMultipartUploadLinksHandler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: extensionServiceMetadata,
+}
+
+//TokenServiceServer Manages the API tokens of a user
+type TokenServiceServer interface {
+	CreateToken(context.Context, *models.CreateTokenRequest) (*models.TokenEntry, error)
+	GetUserTokens(context.Context, *models.Empty) (*models.TokenList, error)
+	RevokeToken(context.Context, *models.ID) (*models.Empty, error)
+}
+
+var tokenServiceDesc = grpc.ServiceDesc{
+	ServiceName: "TokenService",
+	HandlerType: (*TokenServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateToken",
+			Handler: newUnaryHandler("/TokenService/CreateToken", func() interface{} { return new(models.CreateTokenRequest) },
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(TokenServiceServer).CreateToken(ctx, request.(*models.CreateTokenRequest))
+				}),
+		},
+		{
+			MethodName: "GetUserTokens",
+			Handler: newUnaryHandler("/TokenService/GetUserTokens", func() interface{} { return new(models.Empty) },
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(TokenServiceServer).GetUserTokens(ctx, request.(*models.Empty))
+				}),
+		},
+		{
+			MethodName: "RevokeToken",
+			Handler: newUnaryHandler("/TokenService/RevokeToken", newID,
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(TokenServiceServer).RevokeToken(ctx, request.(*models.ID))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: extensionServiceMetadata,
+}
+
+type serverStreamCall func(srv interface{}, request interface{}, stream grpc.ServerStream) error
+
+//newServerStreamHandler Creates a grpc stream handler that receives a single request message before the response messages are streamed
+func newServerStreamHandler(newRequest func() interface{}, call serverStreamCall) grpc.StreamHandler {
+	return func(srv interface{}, stream grpc.ServerStream) error {
+		in := newRequest()
+		if err := stream.RecvMsg(in); err != nil {
+			return err
+		}
+		return call(srv, in, stream)
+	}
+}
+
+//ObjectGroupStream Sends the object groups of a server stream
+type ObjectGroupStream interface {
+	Send(*models.DatasetObjectGroup) error
+	grpc.ServerStream
+}
+
+type objectGroupStream struct {
+	grpc.ServerStream
+}
+
+func (x *objectGroupStream) Send(m *models.DatasetObjectGroup) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+//ObjectGroupStreamServiceServer Streams the object groups of datasets and dataset versions
+type ObjectGroupStreamServiceServer interface {
+	StreamDatasetObjectGroups(*models.ID, ObjectGroupStream) error
+	StreamDatasetVersionObjectGroups(*models.ID, ObjectGroupStream) error
+}
+
+var objectGroupStreamServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ObjectGroupStreamService",
+	HandlerType: (*ObjectGroupStreamServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "StreamDatasetObjectGroups",
+			Handler: newServerStreamHandler(newID, func(srv interface{}, request interface{}, stream grpc.ServerStream) error {
+				return srv.(ObjectGroupStreamServiceServer).StreamDatasetObjectGroups(request.(*models.ID), &objectGroupStream{stream})
+			}),
+			ServerStreams: true,
+		},
+		{
+			StreamName: "StreamDatasetVersionObjectGroups",
+			Handler: newServerStreamHandler(newID, func(srv interface{}, request interface{}, stream grpc.ServerStream) error {
+				return srv.(ObjectGroupStreamServiceServer).StreamDatasetVersionObjectGroups(request.(*models.ID), &objectGroupStream{stream})
+			}),
+			ServerStreams: true,
+		},
+	},
+	Metadata: extensionServiceMetadata,
+}
+
+//ObjectGroupSearchServiceServer Searches object groups by their labels and objects
+//The search query is passed as a Struct with the fields of databasehandler.ObjectGroupSearchQuery, see the README for its schema
+type ObjectGroupSearchServiceServer interface {
+	SearchObjectGroups(context.Context, *structpb.Struct) (*services.ObjectGroupList, error)
+}
+
+var objectGroupSearchServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ObjectGroupSearchService",
+	HandlerType: (*ObjectGroupSearchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchObjectGroups",
+			Handler: newUnaryHandler("/ObjectGroupSearchService/SearchObjectGroups", func() interface{} { return new(structpb.Struct) },
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ObjectGroupSearchServiceServer).SearchObjectGroups(ctx, request.(*structpb.Struct))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: extensionServiceMetadata,
+}
+
+//ProjectSearchServiceServer Searches the datasets and object groups of projects
+//The search request is passed as a Struct with the fields Query, ProjectID and Limit, the hits are returned in the Hits list of the response
+//See the README for the schema of the request and the hits
+type ProjectSearchServiceServer interface {
+	FullTextSearch(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+var projectSearchServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ProjectSearchService",
+	HandlerType: (*ProjectSearchServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "FullTextSearch",
+			Handler: newUnaryHandler("/ProjectSearchService/FullTextSearch", func() interface{} { return new(structpb.Struct) },
+				func(srv interface{}, ctx context.Context, request interface{}) (interface{}, error) {
+					return srv.(ProjectSearchServiceServer).FullTextSearch(ctx, request.(*structpb.Struct))
+				}),
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: extensionServiceMetadata,
+}
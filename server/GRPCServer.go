@@ -11,6 +11,7 @@ import (
 	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
 	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/objectstoragehandler"
 	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/spf13/viper"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -24,6 +25,8 @@ type GenericEndpoints struct {
 	DatasetHandler        *databasehandler.DatasetActionHandler
 	DatasetVersionHandler *databasehandler.DatasetVersionActionHandler
 	ObjectGroupHandler    *databasehandler.ObjectGroupHandler
+	ObjectHeritageHandler *databasehandler.ObjectHeritageHandler
+	TokenHandler          *databasehandler.TokenActionHandler
 }
 
 //GRPCServerHandler handles the grpc server for the API
@@ -49,14 +52,32 @@ func (server *GRPCServerHandler) StartGRPCServer(port int64) error {
 
 //StartGRPCServerWithListener starts a GRPC server based on the provided listener
 func (server *GRPCServerHandler) StartGRPCServerWithListener(listener net.Listener) error {
-	grpcServer := grpc.NewServer()
-
 	genericEndpoints, err := server.initGenericEndpoints()
 	if err != nil {
 		log.Println(err.Error())
 		return err
 	}
 
+	authorizationInterceptor := NewAuthorizationInterceptor(genericEndpoints)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(errorUnaryInterceptor, authorizationInterceptor.Unary),
+		grpc.ChainStreamInterceptor(errorStreamInterceptor, authorizationInterceptor.Stream),
+	)
+
+	if viper.GetBool("Config.GarbageCollection.Enabled") {
+		garbageCollector, err := NewGarbageCollector(genericEndpoints)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go garbageCollector.Start(ctx)
+	}
+
 	projectEndpoints, err := NewProjectEndpoint(genericEndpoints)
 	if err != nil {
 		log.Println(err.Error())
@@ -81,13 +102,32 @@ func (server *GRPCServerHandler) StartGRPCServerWithListener(listener net.Listen
 		return err
 	}
 
+	tokenEndpoints, err := NewTokenEndpoints(genericEndpoints)
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
 	services.RegisterProjectAPIServer(grpcServer, projectEndpoints)
 	services.RegisterDatasetServiceServer(grpcServer, datasetEndpoints)
 	services.RegisterDatasetObjectsServiceServer(grpcServer, objectEndpoints)
 	services.RegisterObjectLoadServer(grpcServer, loadEndpoints)
+	grpcServer.RegisterService(&projectUserServiceDesc, projectEndpoints)
+	grpcServer.RegisterService(&objectHeritageServiceDesc, objectEndpoints)
+	grpcServer.RegisterService(&multipartUploadServiceDesc, loadEndpoints)
+	grpcServer.RegisterService(&tokenServiceDesc, tokenEndpoints)
+	grpcServer.RegisterService(&objectGroupStreamServiceDesc, datasetEndpoints)
+	grpcServer.RegisterService(&objectGroupSearchServiceDesc, objectEndpoints)
+	grpcServer.RegisterService(&projectSearchServiceDesc, projectEndpoints)
 
 	reflection.Register(grpcServer)
 
+	err = authorizationInterceptor.CheckRules(grpcServer.GetServiceInfo())
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
 	log.Println(fmt.Sprintf("Starting grpc server on port: %v", listener.Addr().String()))
 	err = grpcServer.Serve(listener)
 	if err != nil {
@@ -113,8 +153,22 @@ func (server *GRPCServerHandler) initGenericEndpoints() (*GenericEndpoints, erro
 		return nil, err
 	}
 
-	tokenHandler := databasehandler.TokenActionHandler{
-		DBUtilsHandler: dbHandler,
+	err = dbHandler.CheckTransactionSupport()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = dbHandler.Migrate()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	tokenHandler, err := databasehandler.NewTokenActionHandler(dbHandler)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
 	projectHandler := databasehandler.ProjectActionHandler{
@@ -141,7 +195,13 @@ func (server *GRPCServerHandler) initGenericEndpoints() (*GenericEndpoints, erro
 		return nil, err
 	}
 
-	auth, err := authhandler.InitProjectHandler(&projectHandler, &tokenHandler, datasetHandler, datasetVersionHandler, objectGroupHandler)
+	objectHeritageHandler, err := databasehandler.NewObjectHeritageHandler(dbHandler)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	auth, err := authhandler.InitProjectHandler(&projectHandler, tokenHandler, datasetHandler, datasetVersionHandler, objectGroupHandler)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -154,6 +214,8 @@ func (server *GRPCServerHandler) initGenericEndpoints() (*GenericEndpoints, erro
 		DatasetHandler:        datasetHandler,
 		DatasetVersionHandler: datasetVersionHandler,
 		ObjectGroupHandler:    objectGroupHandler,
+		ObjectHeritageHandler: objectHeritageHandler,
+		TokenHandler:          tokenHandler,
 	}
 
 	return &genericEndpoints, nil
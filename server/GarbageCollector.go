@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/viper"
+)
+
+//defaultGarbageCollectionInterval Time between two garbage collection runs if none is configured
+const defaultGarbageCollectionInterval = time.Hour
+
+//defaultGarbageCollectionGracePeriod Minimal age of an object storage key before it can be considered orphaned if none is configured
+const defaultGarbageCollectionGracePeriod = 24 * time.Hour
+
+//GarbageCollector Periodically removes abandoned object groups, object storage keys that are not referenced by any object group
+//and multipart uploads that were neither completed nor aborted
+//In dry run mode the garbage collector only reports what it would delete
+type GarbageCollector struct {
+	*GenericEndpoints
+	Interval    time.Duration
+	GracePeriod time.Duration
+	DryRun      bool
+}
+
+//GarbageCollectionReport The results of a single garbage collection run
+type GarbageCollectionReport struct {
+	StaleObjectGroupIDs       []string
+	OrphanedKeys              []string
+	AbandonedMultipartUploads []string
+}
+
+//NewGarbageCollector Creates a new garbage collector based on the Config.GarbageCollection section of the config
+func NewGarbageCollector(genericEndpoints *GenericEndpoints) (*GarbageCollector, error) {
+	interval := viper.GetDuration("Config.GarbageCollection.Interval")
+	if interval == 0 {
+		interval = defaultGarbageCollectionInterval
+	}
+
+	gracePeriod := viper.GetDuration("Config.GarbageCollection.GracePeriod")
+	if gracePeriod == 0 {
+		gracePeriod = defaultGarbageCollectionGracePeriod
+	}
+
+	dryRun := true
+	if viper.IsSet("Config.GarbageCollection.DryRun") {
+		dryRun = viper.GetBool("Config.GarbageCollection.DryRun")
+	}
+
+	return &GarbageCollector{
+		GenericEndpoints: genericEndpoints,
+		Interval:         interval,
+		GracePeriod:      gracePeriod,
+		DryRun:           dryRun,
+	}, nil
+}
+
+//Start Runs the garbage collector periodically until the context is cancelled
+func (collector *GarbageCollector) Start(ctx context.Context) {
+	ticker := time.NewTicker(collector.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := collector.Run()
+			if err != nil {
+				log.Println(err.Error())
+			}
+		}
+	}
+}
+
+//Run Executes a single garbage collection run
+func (collector *GarbageCollector) Run() (*GarbageCollectionReport, error) {
+	report := GarbageCollectionReport{}
+
+	staleObjectGroupIDs, err := collector.collectStaleObjectGroups()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+	report.StaleObjectGroupIDs = staleObjectGroupIDs
+
+	orphanedKeys, err := collector.collectOrphanedKeys()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+	report.OrphanedKeys = orphanedKeys
+
+	abandonedMultipartUploads, err := collector.collectAbandonedMultipartUploads()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+	report.AbandonedMultipartUploads = abandonedMultipartUploads
+
+	log.Println(fmt.Sprintf("Garbage collection (dry run: %v) found %v stale object groups, %v orphaned keys and %v abandoned multipart uploads",
+		collector.DryRun, len(report.StaleObjectGroupIDs), len(report.OrphanedKeys), len(report.AbandonedMultipartUploads)))
+
+	return &report, nil
+}
+
+//collectStaleObjectGroups Removes object groups that are initiating without progress for longer than the configured timeout
+//Object groups that changed their status since they were found to be stale are skipped
+func (collector *GarbageCollector) collectStaleObjectGroups() ([]string, error) {
+	objectGroups, err := collector.ObjectGroupHandler.GetStaleObjectGroups()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	var staleObjectGroupIDs []string
+
+	for _, objectGroup := range objectGroups {
+		if collector.DryRun {
+			log.Println(fmt.Sprintf("Object group %v would be deleted", objectGroup.GetID()))
+			staleObjectGroupIDs = append(staleObjectGroupIDs, objectGroup.GetID())
+			continue
+		}
+
+		// The upload could have started since the object group was queried
+		err := collector.ObjectGroupHandler.MarkStaleDeleting(objectGroup.GetID())
+		if apierrors.Is(err, apierrors.FailedPrecondition) {
+			log.Println(err.Error())
+			continue
+		}
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		var keys []string
+		for _, object := range objectGroup.GetObjects() {
+			keys = append(keys, object.GetLocation().GetKey())
+		}
+
+		err = collector.ObjectStorageHandler.DeleteObjects(collector.ObjectGroupHandler.BucketName, keys)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		err = collector.ObjectGroupHandler.DeleteObjectGroup(objectGroup.GetID())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		staleObjectGroupIDs = append(staleObjectGroupIDs, objectGroup.GetID())
+	}
+
+	return staleObjectGroupIDs, nil
+}
+
+//collectOrphanedKeys Removes keys of a project from the bucket that are older than the grace period and not referenced by any object group
+//Keys outside of the prefixes of the projects are not managed by the server and are left untouched
+func (collector *GarbageCollector) collectOrphanedKeys() ([]string, error) {
+	var orphanedKeys []string
+
+	projectIDs, err := collector.ProjectActionHandler.GetProjectIDs()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	for _, projectID := range projectIDs {
+		projectOrphanedKeys, err := collector.collectProjectOrphanedKeys(projectID)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		orphanedKeys = append(orphanedKeys, projectOrphanedKeys...)
+	}
+
+	return orphanedKeys, nil
+}
+
+//collectProjectOrphanedKeys Removes keys with the prefix of the given project that are older than the grace period and not referenced by any object group
+func (collector *GarbageCollector) collectProjectOrphanedKeys(projectID string) ([]string, error) {
+	var orphanedKeys []string
+
+	cutoff := time.Now().Add(-collector.GracePeriod)
+	bucket := collector.ObjectGroupHandler.BucketName
+
+	err := collector.ObjectStorageHandler.ListObjectPages(bucket, databasehandler.ObjectKeyPrefix(projectID), func(objects []types.Object) error {
+		var keys []string
+		for _, object := range objects {
+			if object.LastModified != nil && object.LastModified.Before(cutoff) {
+				keys = append(keys, aws.ToString(object.Key))
+			}
+		}
+
+		if len(keys) == 0 {
+			return nil
+		}
+
+		referencedKeys, err := collector.ObjectGroupHandler.GetReferencedObjectKeys(keys)
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		var pageOrphanedKeys []string
+		for _, key := range keys {
+			if !referencedKeys[key] {
+				pageOrphanedKeys = append(pageOrphanedKeys, key)
+			}
+		}
+
+		if collector.DryRun {
+			for _, key := range pageOrphanedKeys {
+				log.Println(fmt.Sprintf("Orphaned key %v would be deleted", key))
+			}
+		} else {
+			err = collector.ObjectStorageHandler.DeleteObjects(bucket, pageOrphanedKeys)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+		}
+
+		orphanedKeys = append(orphanedKeys, pageOrphanedKeys...)
+
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return orphanedKeys, nil
+}
+
+//collectAbandonedMultipartUploads Aborts multipart uploads of a project that were initiated before the grace period and neither completed nor aborted
+//The upload ids of the aborted multipart uploads are removed from their objects
+func (collector *GarbageCollector) collectAbandonedMultipartUploads() ([]string, error) {
+	var abandonedUploads []string
+
+	projectIDs, err := collector.ProjectActionHandler.GetProjectIDs()
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	for _, projectID := range projectIDs {
+		projectAbandonedUploads, err := collector.collectProjectAbandonedMultipartUploads(projectID)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		abandonedUploads = append(abandonedUploads, projectAbandonedUploads...)
+	}
+
+	return abandonedUploads, nil
+}
+
+//collectProjectAbandonedMultipartUploads Aborts multipart uploads with the prefix of the given project that were initiated before the grace period
+func (collector *GarbageCollector) collectProjectAbandonedMultipartUploads(projectID string) ([]string, error) {
+	var abandonedUploads []string
+
+	cutoff := time.Now().Add(-collector.GracePeriod)
+	bucket := collector.ObjectGroupHandler.BucketName
+
+	err := collector.ObjectStorageHandler.ListMultipartUploadPages(bucket, databasehandler.ObjectKeyPrefix(projectID), func(uploads []types.MultipartUpload) error {
+		for _, upload := range uploads {
+			if upload.Initiated == nil || !upload.Initiated.Before(cutoff) {
+				continue
+			}
+
+			uploadID := aws.ToString(upload.UploadId)
+
+			if collector.DryRun {
+				log.Println(fmt.Sprintf("Multipart upload %v of key %v would be aborted", uploadID, aws.ToString(upload.Key)))
+				abandonedUploads = append(abandonedUploads, uploadID)
+				continue
+			}
+
+			err := collector.ObjectStorageHandler.AbortKeyMultipartUpload(bucket, aws.ToString(upload.Key), uploadID)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			err = collector.ObjectGroupHandler.ClearAbandonedMultipartUpload(uploadID)
+			if err != nil {
+				log.Println(err.Error())
+				return err
+			}
+
+			abandonedUploads = append(abandonedUploads, uploadID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return abandonedUploads, nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/objectstoragehandler"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/util"
+	"github.com/ScienceObjectsDB/go-api/services"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/spf13/viper"
+)
+
+func TestGarbageCollector_RunDryRun(t *testing.T) {
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "minioadmin")
+	os.Setenv("AWS_ACCESS_KEY_ID", "minioadmin")
+
+	viper.Set("Config.S3.Bucketname", "testbucket")
+
+	err := util.InitTestEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	client, err := databasehandler.NewMongoClient(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbHandler, err := databasehandler.NewDBUtilsHandler(client, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectHandler := &databasehandler.ProjectActionHandler{
+		DBUtilsHandler: dbHandler,
+	}
+
+	datasetHandler, err := databasehandler.NewDatasetHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroupHandler, err := databasehandler.NewObjectGroupHandler(dbHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectGroupHandler.InitiatingTimeout = -time.Hour
+
+	objectStorageHandler, err := objectstoragehandler.NewS3Handler()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := projectHandler.CreateProject("testuser", &services.CreateProjectRequest{
+		Name: "garbagecollection",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataset, err := datasetHandler.CreateNewDataset(&services.CreateDatasetRequest{
+		DatasetName: "garbagecollection",
+		Datatype:    "txt",
+		ProjectID:   project.GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objectGroup, err := objectGroupHandler.CreateDatasetObjectGroupObject(&services.CreateObjectGroupRequest{
+		Name:      "stale",
+		DatasetID: dataset.GetID(),
+		Objects: []*services.CreateObjectRequest{
+			{
+				Filename:   "stalefile",
+				Filetype:   "txt",
+				ContentLen: 4,
+			},
+		},
+	}, project.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orphanedKey := databasehandler.ObjectKeyPrefix(project.GetID(), "orphaned") + "orphanedfile"
+
+	_, err = objectStorageHandler.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String(orphanedKey),
+		Body:   strings.NewReader("data"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := GarbageCollector{
+		GenericEndpoints: &GenericEndpoints{
+			ObjectStorageHandler: objectStorageHandler,
+			ProjectActionHandler: projectHandler,
+			DatasetHandler:       datasetHandler,
+			ObjectGroupHandler:   objectGroupHandler,
+		},
+		GracePeriod: -time.Hour,
+		DryRun:      true,
+	}
+
+	report, err := collector.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsString(report.StaleObjectGroupIDs, objectGroup.GetID()) {
+		t.Errorf("Stale object group %v was not reported: %v", objectGroup.GetID(), report.StaleObjectGroupIDs)
+	}
+
+	if !containsString(report.OrphanedKeys, orphanedKey) {
+		t.Errorf("Orphaned key %v was not reported: %v", orphanedKey, report.OrphanedKeys)
+	}
+
+	_, err = objectGroupHandler.GetObjectGroup(objectGroup.GetID())
+	if err != nil {
+		t.Errorf("Object group %v was deleted in a dry run: %v", objectGroup.GetID(), err)
+	}
+
+	_, err = objectStorageHandler.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String("testbucket"),
+		Key:    aws.String(orphanedKey),
+	})
+	if err != nil {
+		t.Errorf("Orphaned key %v was deleted in a dry run: %v", orphanedKey, err)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, element := range values {
+		if element == value {
+			return true
+		}
+	}
+
+	return false
+}
\ No newline at end of file
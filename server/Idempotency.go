@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
+	"github.com/ScienceObjectsDB/go-api/models"
+)
+
+//IdempotencyKeyMetadataKey Request metadata key of the client-supplied idempotency key of create requests
+//A retried create request with the same key returns the entry that was created by the first request
+const IdempotencyKeyMetadataKey = "IdempotencyKey"
+
+//idempotencyKey Reads the idempotency key from the request metadata, it is empty if the request has none
+func idempotencyKey(ctx context.Context) string {
+	meta, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	key := meta.Get(IdempotencyKeyMetadataKey)
+	if len(key) == 0 {
+		return ""
+	}
+
+	return key[0]
+}
+
+//createIdempotent Runs create once per idempotency key of the requesting user
+//Returns the ID of the resource and whether it was created by this request, requests without a key always create a new resource
+//The key can only be reused for the same request, which is identified by the hash of its deterministic serialization
+func (endpoints *GenericEndpoints) createIdempotent(ctx context.Context, resource models.Resource, request proto.Message, create databasehandler.IdempotentCreate) (string, bool, error) {
+	key := idempotencyKey(ctx)
+	if key == "" {
+		id, err := create(endpoints.ProjectActionHandler.DBUtilsHandler)
+		return id, err == nil, err
+	}
+
+	userID, err := endpoints.AuthHandler.UserID(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return "", false, err
+	}
+
+	requestHash, err := hashRequest(request)
+	if err != nil {
+		log.Println(err.Error())
+		return "", false, err
+	}
+
+	return endpoints.ProjectActionHandler.CreateIdempotent(resource, userID, key, requestHash, create)
+}
+
+//hashRequest Returns the hex encoded sha256 hash of the deterministic serialization of a request
+func hashRequest(request proto.Message) (string, error) {
+	serializedRequest, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(serializedRequest)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ScienceObjectsDB/go-api/services"
+)
+
+func TestHashRequest(t *testing.T) {
+	hash, err := hashRequest(&services.CreateDatasetRequest{DatasetName: "dataset", ProjectID: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sameHash, err := hashRequest(&services.CreateDatasetRequest{DatasetName: "dataset", ProjectID: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash != sameHash {
+		t.Errorf("Equal requests have different hashes %v and %v", hash, sameHash)
+	}
+
+	otherHash, err := hashRequest(&services.CreateDatasetRequest{DatasetName: "otherdataset", ProjectID: "project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hash == otherHash {
+		t.Errorf("Different requests have the same hash %v", hash)
+	}
+}
@@ -2,12 +2,12 @@ package server
 
 import (
 	"context"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
+	"google.golang.org/protobuf/proto"
 )
 
 //LoadEndpoints for the ObjectLoad service of the API
@@ -26,25 +26,41 @@ func NewLoadEndpoints(genericEndpoints *GenericEndpoints) (*LoadEndpoints, error
 
 //CreateUploadLink Returns an upload link for an individual object
 func (endpoint *LoadEndpoints) CreateUploadLink(ctx context.Context, id *models.ID) (*services.CreateUploadLinkResponse, error) {
-	authorized, err := endpoint.AuthHandler.Authorize(ctx, models.Resource_DatasetObject, models.Right_Write, id.GetID())
+	objectGroupID, object, err := endpoint.GenericEndpoints.ObjectGroupHandler.GetObject(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_DatasetObject, id.GetID())
+	err = endpoint.GenericEndpoints.ObjectGroupHandler.StartUpload(objectGroupID)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
+	link, err := endpoint.GenericEndpoints.ObjectStorageHandler.CreatePresignedUploadLink(object)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	uploadLinkResponse := services.CreateUploadLinkResponse{
+		UploadLink: link,
+		Object:     object,
+	}
+
+	return &uploadLinkResponse, nil
+}
+
+//CreateDownloadLink Returns an download link for an individual object
+func (endpoint *LoadEndpoints) CreateDownloadLink(ctx context.Context, id *models.ID) (*services.CreateUploadLinkResponse, error) {
 	_, object, err := endpoint.GenericEndpoints.ObjectGroupHandler.GetObject(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	link, err := endpoint.GenericEndpoints.ObjectStorageHandler.CreatePresignedUploadLink(object)
+	link, err := endpoint.GenericEndpoints.ObjectStorageHandler.CreatePresignedDownloadLink(object)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -58,38 +74,139 @@ func (endpoint *LoadEndpoints) CreateUploadLink(ctx context.Context, id *models.
 	return &uploadLinkResponse, nil
 }
 
-//CreateDownloadLink Returns an download link for an individual object
-func (endpoint *LoadEndpoints) CreateDownloadLink(ctx context.Context, id *models.ID) (*services.CreateUploadLinkResponse, error) {
-	authorized, err := endpoint.AuthHandler.Authorize(ctx, models.Resource_DatasetObject, models.Right_Read, id.GetID())
+//InitiateMultipartUpload Starts a multipart upload for an individual object
+//The upload id of the multipart upload is stored as UploadID of the returned object
+func (endpoint *LoadEndpoints) InitiateMultipartUpload(ctx context.Context, id *models.ID) (*models.DatasetObjectEntry, error) {
+	objectGroupID, object, err := endpoint.ObjectGroupHandler.GetObject(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Read, models.Resource_DatasetObject, id.GetID())
+	err = endpoint.ObjectGroupHandler.StartUpload(objectGroupID)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	_, object, err := endpoint.GenericEndpoints.ObjectGroupHandler.GetObject(id.GetID())
+	uploadID, err := endpoint.ObjectStorageHandler.InitiateMultipartUpload(object)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	link, err := endpoint.GenericEndpoints.ObjectStorageHandler.CreatePresignedDownloadLink(object)
+	err = endpoint.ObjectGroupHandler.StartMultipartUpload(object.GetID(), uploadID)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	uploadLinkResponse := services.CreateUploadLinkResponse{
-		UploadLink: link,
-		Object:     object,
+	object.UploadID = uploadID
+
+	return object, nil
+}
+
+//CreateMultipartUploadLinks Streams the upload links for all parts of a started multipart upload
+//The links are sent in the order of their part numbers starting at 1,
+//the byte range of each part is given as IndexLocation of the object location with an exclusive EndByte
+//Fails if no multipart upload was initiated for the object
+func (endpoint *LoadEndpoints) CreateMultipartUploadLinks(id *models.ID, stream MultipartUploadService_CreateMultipartUploadLinksServer) error {
+	_, object, err := endpoint.ObjectGroupHandler.GetObject(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return err
 	}
 
-	return &uploadLinkResponse, nil
+	uploadID, err := endpoint.ObjectGroupHandler.GetMultipartUploadID(object.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return err
+	}
+
+	for i, part := range endpoint.ObjectStorageHandler.MultipartUploadParts(object.GetContentLen()) {
+		link, err := endpoint.ObjectStorageHandler.CreatePresignedUploadPartLink(object, uploadID, int32(i+1))
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+
+		partObject := proto.Clone(object).(*models.DatasetObjectEntry)
+		if partObject.Location == nil {
+			partObject.Location = &models.Location{}
+		}
+		partObject.Location.IndexLocation = part
+
+		err = stream.Send(&services.CreateUploadLinkResponse{
+			UploadLink: link,
+			Object:     partObject,
+		})
+		if err != nil {
+			log.Println(err.Error())
+			return err
+		}
+	}
+
+	return nil
+}
+
+//CompleteMultipartUpload Completes the multipart upload of an individual object
+//Fails if no multipart upload was initiated for the object
+func (endpoint *LoadEndpoints) CompleteMultipartUpload(ctx context.Context, id *models.ID) (*models.Empty, error) {
+	_, object, err := endpoint.ObjectGroupHandler.GetObject(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	uploadID, err := endpoint.ObjectGroupHandler.GetMultipartUploadID(object.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectStorageHandler.CompleteMultipartUpload(object, uploadID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectGroupHandler.FinishMultipartUpload(object.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &models.Empty{}, nil
+}
+
+//AbortMultipartUpload Aborts the multipart upload of an individual object and removes its upload id from the object
+//Fails if no multipart upload was initiated for the object
+func (endpoint *LoadEndpoints) AbortMultipartUpload(ctx context.Context, id *models.ID) (*models.Empty, error) {
+	_, object, err := endpoint.ObjectGroupHandler.GetObject(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	uploadID, err := endpoint.ObjectGroupHandler.GetMultipartUploadID(object.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectStorageHandler.AbortMultipartUpload(object, uploadID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectGroupHandler.ClearMultipartUpload(object.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &models.Empty{}, nil
 }
 
 func (endpoint *LoadEndpoints) mustEmbedUnimplementedObjectLoadServer() {
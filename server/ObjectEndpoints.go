@@ -3,13 +3,22 @@ package server
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+//UploadErrorMetadataKey Response header key of the reason why the last upload of an object group failed, it is omitted if no upload failed
+const UploadErrorMetadataKey = "UploadError"
+
 //ObjectEndpoints Handles object related gRPC endpoints
 type ObjectEndpoints struct {
 	*GenericEndpoints
@@ -25,51 +34,126 @@ func NewObjectEndpoints(genericEndpoints *GenericEndpoints) (*ObjectEndpoints, e
 
 //CreateObjectHeritage Creates a new object heritage
 func (endpoints *ObjectEndpoints) CreateObjectHeritage(ctx context.Context, request *services.CreateObjectHeritageRequest) (*models.ObjectHeritage, error) {
-	panic("not implemented") // TODO: Implement
+	objectHeritage, err := endpoints.ObjectHeritageHandler.CreateObjectHeritage(request)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return objectHeritage, nil
 }
 
-//CreateObjectGroup Creates a new object group
-func (endpoints *ObjectEndpoints) CreateObjectGroup(ctx context.Context, request *services.CreateObjectGroupRequest) (*models.DatasetObjectGroup, error) {
-	authorized, err := endpoints.AuthHandler.Authorize(ctx, models.Resource_Dataset, models.Right_Write, request.GetDatasetID())
+//GetObjectHeritage Returns the object heritage with the given ID
+func (endpoints *ObjectEndpoints) GetObjectHeritage(ctx context.Context, id *models.ID) (*models.ObjectHeritage, error) {
+	objectHeritage, err := endpoints.ObjectHeritageHandler.GetObjectHeritage(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_Project, request.GetDatasetID())
+	return objectHeritage, nil
+}
+
+//GetObjectHeritageObjectGroups Returns all object groups of the object heritage with the given ID
+func (endpoints *ObjectEndpoints) GetObjectHeritageObjectGroups(ctx context.Context, id *models.ID) (*services.ObjectGroupList, error) {
+	groups, err := endpoints.ObjectHeritageHandler.GetObjectHeritageObjectGroups(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	objectGroupList := services.ObjectGroupList{
+		ObjectGroups: groups,
+	}
+
+	return &objectGroupList, nil
+}
+
+//GetRelatedObjectGroups Returns all object groups that share the object heritage of the object group with the given ID
+func (endpoints *ObjectEndpoints) GetRelatedObjectGroups(ctx context.Context, id *models.ID) (*services.ObjectGroupList, error) {
+	groups, err := endpoints.ObjectHeritageHandler.GetRelatedObjectGroups(id.GetID())
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
+	}
+
+	objectGroupList := services.ObjectGroupList{
+		ObjectGroups: groups,
+	}
+
+	return &objectGroupList, nil
+}
 
+//CreateObjectGroup Creates a new object group
+//A retried request with the same IdempotencyKey metadata returns the object group that was created by the first request
+func (endpoints *ObjectEndpoints) CreateObjectGroup(ctx context.Context, request *services.CreateObjectGroupRequest) (*models.DatasetObjectGroup, error) {
+	if request.GetObjectHeritageID() != "" {
+		err := endpoints.ObjectHeritageHandler.CheckObjectHeritageDataset(request.GetObjectHeritageID(), request.GetDatasetID())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
 	}
 
 	projectID, err := endpoints.GenericEndpoints.DatasetHandler.GetDatasetProjectID(request.GetDatasetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
 
-	entry, err := endpoints.GenericEndpoints.ObjectGroupHandler.CreateDatasetObjectGroupObject(request, projectID)
+	var entry *models.DatasetObjectGroup
+
+	objectGroupID, created, err := endpoints.createIdempotent(ctx, models.Resource_DatasetObjectGroupResource, request, func(handler *databasehandler.DBUtilsHandler) (string, error) {
+		objectGroupHandler := *endpoints.GenericEndpoints.ObjectGroupHandler
+		objectGroupHandler.DBUtilsHandler = handler
+
+		var err error
+		entry, err = objectGroupHandler.CreateDatasetObjectGroupObject(request, projectID)
+		return entry.GetID(), err
+	})
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
+	if !created {
+		return endpoints.GenericEndpoints.ObjectGroupHandler.GetObjectGroup(objectGroupID)
+	}
+
 	return entry, nil
 }
 
 //FinishObjectUpload Finishes the upload process for a data
+//Every object of the object group has to be present in the object storage with its announced content length,
+//otherwise the request is rejected and the reason is recorded as upload error of the object group
 func (endpoints *ObjectEndpoints) FinishObjectUpload(ctx context.Context, id *models.ID) (*models.Empty, error) {
-	authorized, err := endpoints.AuthHandler.Authorize(ctx, models.Resource_DatasetObjectGroupResource, models.Right_Write, id.GetID())
+	objectGroup, err := endpoints.GenericEndpoints.ObjectGroupHandler.GetObjectGroup(id.GetID())
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Write, models.Resource_Project, id.GetID())
+	uploadedObjects, failedObjects, err := endpoints.verifyObjectUploads(objectGroup.GetObjects())
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
+	}
+
+	if len(failedObjects) != 0 {
+		uploadErr := apierrors.New(apierrors.FailedPrecondition, "Can not finish upload of object group %v, %v of %v objects are not uploaded correctly: %v",
+			id.GetID(), len(failedObjects), len(objectGroup.GetObjects()), strings.Join(failedObjects, ", "))
 
+		err = endpoints.GenericEndpoints.ObjectGroupHandler.FailUpload(id.GetID(), uploadedObjects, uploadErr.Error())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		log.Println(uploadErr.Error())
+		return nil, uploadErr
 	}
 
-	err = endpoints.GenericEndpoints.ObjectGroupHandler.FinishUpload(id.GetID())
+	err = endpoints.GenericEndpoints.ObjectGroupHandler.FinishUpload(id.GetID(), uploadedObjects)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -78,28 +162,139 @@ func (endpoints *ObjectEndpoints) FinishObjectUpload(ctx context.Context, id *mo
 	return &models.Empty{}, nil
 }
 
+//verifyObjectUploads Checks that the given objects are present in the object storage with their announced content length
+//Returns the number of correctly uploaded objects and a description of every failed object
+func (endpoints *ObjectEndpoints) verifyObjectUploads(objects []*models.DatasetObjectEntry) (int64, []string, error) {
+	var uploadedObjects int64
+	var failedObjects []string
+
+	for _, object := range objects {
+		size, exists, err := endpoints.ObjectStorageHandler.GetObjectSize(object)
+		if err != nil {
+			log.Println(err.Error())
+			return 0, nil, err
+		}
+
+		switch {
+		case !exists:
+			failedObjects = append(failedObjects, fmt.Sprintf("%v is missing", object.GetID()))
+		case size != object.GetContentLen():
+			failedObjects = append(failedObjects, fmt.Sprintf("%v has %v bytes instead of %v", object.GetID(), size, object.GetContentLen()))
+		default:
+			uploadedObjects++
+		}
+	}
+
+	return uploadedObjects, failedObjects, nil
+}
+
 //GetObjectGroup Returns an object based on the given ID
+//If the last upload of an uploading object group failed, the reason is returned in the UploadError header
 func (endpoints *ObjectEndpoints) GetObjectGroup(ctx context.Context, id *models.ID) (*models.DatasetObjectGroup, error) {
-	authorized, err := endpoints.AuthHandler.Authorize(ctx, models.Resource_DatasetObjectGroupResource, models.Right_Read, id.GetID())
+	objectGroup, err := endpoints.GenericEndpoints.ObjectGroupHandler.GetObjectGroup(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if objectGroup.GetStatus() == databasehandler.ObjectGroupStatusUploading {
+		uploadError, err := endpoints.GenericEndpoints.ObjectGroupHandler.GetUploadError(id.GetID())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		err = setUploadError(ctx, uploadError)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+	}
+
+	return objectGroup, nil
+}
+
+//setUploadError Returns why the last upload of an object group failed in the response header
+func setUploadError(ctx context.Context, uploadError string) error {
+	if uploadError == "" {
+		return nil
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(UploadErrorMetadataKey, uploadError))
+}
+
+//SearchObjectGroups Returns a page of the object groups of a dataset or project that match the search query
+//The page is requested with the PageSize and PageToken metadata, the token of the next page is returned in the NextPageToken header
+func (endpoints *ObjectEndpoints) SearchObjectGroups(ctx context.Context, request *structpb.Struct) (*services.ObjectGroupList, error) {
+	query, err := objectGroupSearchQuery(request)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	// The resource the search is scoped to is part of the request, so it can not be checked by the authorization interceptor
+	resource, resourceID := models.Resource_Dataset, query.DatasetID
+	if query.ProjectID != "" {
+		resource, resourceID = models.Resource_Project, query.ProjectID
+	}
+
+	authorized, err := endpoints.AuthHandler.Authorize(ctx, resource, models.Right_Read, resourceID)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
 	if !authorized {
-		err := fmt.Errorf("Access denied: Can not authorize %v access to %v %v", models.Right_Read, models.Resource_DatasetObjectGroupResource, id.GetID())
+		err := apierrors.NewPermissionDenied(models.Right_Read, resource, resourceID)
 		log.Println(err.Error())
 		return nil, err
+	}
 
+	page, err := pageRequest(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
 	}
 
-	objectGroup, err := endpoints.GenericEndpoints.ObjectGroupHandler.GetObjectGroup(id.GetID())
+	// Unlike the lists of the original API, search results are always paged
+	if page == nil {
+		page = &databasehandler.PageRequest{}
+	}
+
+	groups, nextPageToken, err := endpoints.ObjectGroupHandler.SearchObjectGroups(query, page)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	return objectGroup, nil
+	err = setNextPageToken(ctx, nextPageToken)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	objectGroupList := services.ObjectGroupList{
+		ObjectGroups: groups,
+	}
+
+	return &objectGroupList, nil
+}
+
+//objectGroupSearchQuery Decodes a search query from its Struct representation
+//Creation times are given as RFC 3339 strings
+func objectGroupSearchQuery(request *structpb.Struct) (*databasehandler.ObjectGroupSearchQuery, error) {
+	query := databasehandler.ObjectGroupSearchQuery{}
+
+	err := decodeStruct(request, &query)
+	if err != nil {
+		return nil, err
+	}
+
+	if (query.ProjectID == "") == (query.DatasetID == "") {
+		return nil, apierrors.New(apierrors.InvalidArgument, "Exactly one of ProjectID and DatasetID has to be set")
+	}
+
+	return &query, nil
 }
 
 func (endpoints *ObjectEndpoints) mustEmbedUnimplementedDatasetObjectsServiceServer() {
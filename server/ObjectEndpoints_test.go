@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+//headerTransportStream Records the headers that are set by an endpoint
+type headerTransportStream struct {
+	header metadata.MD
+}
+
+func (stream *headerTransportStream) Method() string {
+	return "/DatasetObjectsService/GetObjectGroup"
+}
+
+func (stream *headerTransportStream) SetHeader(md metadata.MD) error {
+	stream.header = metadata.Join(stream.header, md)
+	return nil
+}
+
+func (stream *headerTransportStream) SendHeader(md metadata.MD) error {
+	return stream.SetHeader(md)
+}
+
+func (stream *headerTransportStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
+func TestObjectGroupSearchQuery(t *testing.T) {
+	request, err := structpb.NewStruct(map[string]interface{}{
+		"DatasetID":    "dataset",
+		"Labels":       []interface{}{map[string]interface{}{"Key": "sample", "Value": "XYZ"}},
+		"Filetype":     "fastq",
+		"CreatedAfter": "2021-03-01T00:00:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := objectGroupSearchQuery(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if query.DatasetID != "dataset" || query.Filetype != "fastq" {
+		t.Errorf("Search query was not decoded correctly: %+v", query)
+	}
+
+	if len(query.Labels) != 1 || query.Labels[0].GetKey() != "sample" || query.Labels[0].GetValue() != "XYZ" {
+		t.Errorf("Labels were not decoded correctly: %v", query.Labels)
+	}
+
+	if query.CreatedAfter == nil || query.CreatedAfter.Unix() != 1614556800 {
+		t.Errorf("Creation time was not decoded correctly: %v", query.CreatedAfter)
+	}
+
+	invalidRequests := map[string]map[string]interface{}{
+		"unknown field":  {"DatasetID": "dataset", "Unknown": "value"},
+		"missing scope":  {"Filename": "file.txt"},
+		"both scopes":    {"DatasetID": "dataset", "ProjectID": "project"},
+		"malformed time": {"DatasetID": "dataset", "CreatedBefore": "yesterday"},
+	}
+
+	for name, fields := range invalidRequests {
+		request, err := structpb.NewStruct(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = objectGroupSearchQuery(request)
+		if !apierrors.Is(err, apierrors.InvalidArgument) {
+			t.Errorf("Expected invalid argument error for %v, got: %v", name, err)
+		}
+	}
+}
+
+func TestSetUploadError(t *testing.T) {
+	stream := &headerTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+
+	err := setUploadError(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stream.header.Get(UploadErrorMetadataKey)) != 0 {
+		t.Errorf("Upload error header was set without a failed upload: %v", stream.header)
+	}
+
+	err = setUploadError(ctx, "1 of 2 objects are not uploaded correctly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uploadErrors := stream.header.Get(UploadErrorMetadataKey)
+	if len(uploadErrors) != 1 || uploadErrors[0] != "1 of 2 objects are not uploaded correctly" {
+		t.Errorf("Unexpected upload error header: %v", uploadErrors)
+	}
+}
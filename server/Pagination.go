@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
+)
+
+//PageSizeMetadataKey Request metadata key of the requested page size of list requests
+const PageSizeMetadataKey = "PageSize"
+
+//PageTokenMetadataKey Request metadata key of the page token of list requests, it is omitted for the first page
+const PageTokenMetadataKey = "PageToken"
+
+//NextPageTokenMetadataKey Response header key of the token of the next page, it is omitted for the last page
+const NextPageTokenMetadataKey = "NextPageToken"
+
+//pageRequest Reads the requested page of a list request from the request metadata
+//Returns nil if neither a page size nor a page token is sent, clients that do not page receive all entries of the list
+func pageRequest(ctx context.Context) (*databasehandler.PageRequest, error) {
+	meta, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	pageSize := meta.Get(PageSizeMetadataKey)
+	pageToken := meta.Get(PageTokenMetadataKey)
+	if len(pageSize) == 0 && len(pageToken) == 0 {
+		return nil, nil
+	}
+
+	page := databasehandler.PageRequest{}
+
+	if len(pageSize) > 0 {
+		size, err := strconv.ParseInt(pageSize[0], 10, 64)
+		if err != nil || size < 0 {
+			return nil, apierrors.New(apierrors.InvalidArgument, "Invalid page size %v", pageSize[0])
+		}
+
+		page.PageSize = size
+	}
+
+	if len(pageToken) > 0 {
+		page.PageToken = pageToken[0]
+	}
+
+	return &page, nil
+}
+
+//setNextPageToken Returns the token of the next page in the response header
+func setNextPageToken(ctx context.Context, nextPageToken string) error {
+	if nextPageToken == "" {
+		return nil
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(NextPageTokenMetadataKey, nextPageToken))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPageRequest(t *testing.T) {
+	page, err := pageRequest(context.Background())
+	if err != nil || page != nil {
+		t.Errorf("Expected no page request without metadata, got %v, %v", page, err)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("Other", "value"))
+	page, err = pageRequest(ctx)
+	if err != nil || page != nil {
+		t.Errorf("Expected no page request without paging metadata, got %v, %v", page, err)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(PageSizeMetadataKey, "10", PageTokenMetadataKey, "token"))
+	page, err = pageRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page == nil || page.PageSize != 10 || page.PageToken != "token" {
+		t.Errorf("Expected page of size 10 with token, got %v", page)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(PageTokenMetadataKey, "token"))
+	page, err = pageRequest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page == nil || page.PageSize != 0 || page.PageToken != "token" {
+		t.Errorf("Expected page with default size and token, got %v", page)
+	}
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(PageSizeMetadataKey, "-1"))
+	_, err = pageRequest(ctx)
+	if !apierrors.Is(err, apierrors.InvalidArgument) {
+		t.Errorf("Expected invalid argument error for negative page size, got: %v", err)
+	}
+}
@@ -2,14 +2,25 @@ package server
 
 import (
 	"context"
-	"fmt"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/authhandler"
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/databasehandler"
 	"github.com/ScienceObjectsDB/go-api/models"
 	"github.com/ScienceObjectsDB/go-api/services"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+//fullTextSearchRequest The fields of a full-text search request
+//Without a ProjectID all projects of the user are searched
+type fullTextSearchRequest struct {
+	Query     string
+	ProjectID string
+	Limit     int64
+}
+
 //ProjectEndpoints Handles project related gRPC endpoints
 type ProjectEndpoints struct {
 	*GenericEndpoints
@@ -26,6 +37,7 @@ func NewProjectEndpoint(genericEndpoints *GenericEndpoints) (*ProjectEndpoints,
 }
 
 //CreateProject creates a new projects
+//A retried request with the same IdempotencyKey metadata returns the project that was created by the first request
 func (endpoint *ProjectEndpoints) CreateProject(ctx context.Context, request *services.CreateProjectRequest) (*models.ProjectEntry, error) {
 	userID, err := endpoint.AuthHandler.UserID(ctx)
 	if err != nil {
@@ -33,29 +45,81 @@ func (endpoint *ProjectEndpoints) CreateProject(ctx context.Context, request *se
 		return nil, err
 	}
 
-	return endpoint.ProjectActionHandler.CreateProject(userID, request)
+	var project *models.ProjectEntry
+
+	projectID, created, err := endpoint.createIdempotent(ctx, models.Resource_Project, request, func(handler *databasehandler.DBUtilsHandler) (string, error) {
+		projectHandler := databasehandler.ProjectActionHandler{
+			DBUtilsHandler: handler,
+		}
+
+		var err error
+		project, err = projectHandler.CreateProject(userID, request)
+		return project.GetID(), err
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if !created {
+		return endpoint.ProjectActionHandler.GetProject(projectID)
+	}
+
+	return project, nil
 }
 
 //AddUserToProject Adds a new user to a given project
-func (endpoint *ProjectEndpoints) AddUserToProject(_ context.Context, _ *services.AddUserToProjectRequest) (*models.ProjectEntry, error) {
-	panic("not implemented") // TODO: Implement
+//Requires write access to the project
+func (endpoint *ProjectEndpoints) AddUserToProject(ctx context.Context, request *services.AddUserToProjectRequest) (*models.ProjectEntry, error) {
+	project, err := endpoint.ProjectActionHandler.AddUserToProject(request.GetUserID(), request.GetProjectID(), request.GetScope())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return project, nil
+}
+
+//RemoveUserFromProject Removes a user from a given project
+//Requires write access to the project, the scope of the request is ignored
+func (endpoint *ProjectEndpoints) RemoveUserFromProject(ctx context.Context, request *services.AddUserToProjectRequest) (*models.ProjectEntry, error) {
+	project, err := endpoint.ProjectActionHandler.RemoveUserFromProject(request.GetUserID(), request.GetProjectID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return project, nil
 }
 
-//GetProjectDatasets Returns all datasets that belong to a certain project
+//ChangeUserRights Replaces the rights of a user in a given project with the scope of the request
+//Requires write access to the project
+func (endpoint *ProjectEndpoints) ChangeUserRights(ctx context.Context, request *services.AddUserToProjectRequest) (*models.ProjectEntry, error) {
+	project, err := endpoint.ProjectActionHandler.ChangeUserRights(request.GetUserID(), request.GetProjectID(), request.GetScope())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return project, nil
+}
+
+//GetProjectDatasets Returns the datasets that belong to a certain project
+//Only a page is returned if the PageSize or PageToken metadata is sent, the token of the next page is returned in the NextPageToken header
 func (endpoint *ProjectEndpoints) GetProjectDatasets(ctx context.Context, id *models.ID) (*services.DatasetList, error) {
-	authorized, err := endpoint.AuthHandler.Authorize(ctx, models.Resource_Project, models.Right_Read, id.GetID())
+	page, err := pageRequest(ctx)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	if !authorized {
-		err := fmt.Errorf("Access on dataset with ID %v denied", id.GetID())
+	datasets, nextPageToken, err := endpoint.ProjectActionHandler.GetProjectDatasets(id.GetID(), page)
+	if err != nil {
 		log.Println(err.Error())
 		return nil, err
 	}
 
-	datasets, err := endpoint.ProjectActionHandler.GetProjectDatasets(id.GetID())
+	err = setNextPageToken(ctx, nextPageToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -68,7 +132,8 @@ func (endpoint *ProjectEndpoints) GetProjectDatasets(ctx context.Context, id *mo
 	return &datasetList, nil
 }
 
-//GetUserProjects Returns all projects that a specified user has access to
+//GetUserProjects Returns the projects that a specified user has access to
+//Only a page is returned if the PageSize or PageToken metadata is sent, the token of the next page is returned in the NextPageToken header
 func (endpoint *ProjectEndpoints) GetUserProjects(ctx context.Context, _ *models.Empty) (*services.ProjectEntryList, error) {
 	userID, err := endpoint.AuthHandler.UserID(ctx)
 	if err != nil {
@@ -76,7 +141,19 @@ func (endpoint *ProjectEndpoints) GetUserProjects(ctx context.Context, _ *models
 		return nil, err
 	}
 
-	projects, err := endpoint.ProjectActionHandler.GetUserProjects(userID)
+	page, err := pageRequest(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	projects, nextPageToken, err := endpoint.ProjectActionHandler.GetUserProjects(userID, page)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = setNextPageToken(ctx, nextPageToken)
 	if err != nil {
 		log.Println(err.Error())
 		return nil, err
@@ -89,8 +166,128 @@ func (endpoint *ProjectEndpoints) GetUserProjects(ctx context.Context, _ *models
 	return &projectList, nil
 }
 
+//FullTextSearch Searches dataset names, object filenames and object group metadata of the projects the user can read
+//Returns the hits ordered by their relevance, every hit contains Resource, ID, Name, ProjectID, DatasetID and Score
+func (endpoint *ProjectEndpoints) FullTextSearch(ctx context.Context, request *structpb.Struct) (*structpb.Struct, error) {
+	searchRequest := fullTextSearchRequest{}
+
+	err := decodeStruct(request, &searchRequest)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	projectIDs, err := endpoint.readableProjectIDs(ctx, searchRequest.ProjectID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	hits, err := endpoint.ProjectActionHandler.FullTextSearch(projectIDs, searchRequest.Query, searchRequest.Limit)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	hitValues := make([]interface{}, 0, len(hits))
+	for _, hit := range hits {
+		hitValues = append(hitValues, map[string]interface{}{
+			"Resource":  hit.Resource.String(),
+			"ID":        hit.ID,
+			"Name":      hit.Name,
+			"ProjectID": hit.ProjectID,
+			"DatasetID": hit.DatasetID,
+			"Score":     hit.Score,
+		})
+	}
+
+	response, err := structpb.NewStruct(map[string]interface{}{
+		"Hits": hitValues,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return response, nil
+}
+
+//readableProjectIDs Returns the requested project or all projects of the user, reduced to the projects the request is authorized to read
+//The readable projects of users are found with a single query, API tokens are authorized per project to respect their scope
+func (endpoint *ProjectEndpoints) readableProjectIDs(ctx context.Context, projectID string) ([]string, error) {
+	if projectID != "" {
+		authorized, err := endpoint.AuthHandler.Authorize(ctx, models.Resource_Project, models.Right_Read, projectID)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		if !authorized {
+			return nil, apierrors.NewPermissionDenied(models.Right_Read, models.Resource_Project, projectID)
+		}
+
+		return []string{projectID}, nil
+	}
+
+	tokenType, err := endpoint.AuthHandler.TokenType(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	userID, err := endpoint.AuthHandler.UserID(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	candidateIDs, err := endpoint.ProjectActionHandler.GetUserProjectIDs(userID, models.Right_Read)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	if tokenType == authhandler.OAuth2Token {
+		return candidateIDs, nil
+	}
+
+	var projectIDs []string
+
+	for _, candidateID := range candidateIDs {
+		authorized, err := endpoint.AuthHandler.Authorize(ctx, models.Resource_Project, models.Right_Read, candidateID)
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		if authorized {
+			projectIDs = append(projectIDs, candidateID)
+		}
+	}
+
+	return projectIDs, nil
+}
+
 //DeleteProject Deletes a specific project
 //Will also delete all associated resources (Datasets/Objects/etc...) both from objects storage and the database
-func (endpoint *ProjectEndpoints) DeleteProject(_ context.Context, _ *models.ID) (*models.Empty, error) {
-	panic("not implemented") // TODO: Implement
+func (endpoint *ProjectEndpoints) DeleteProject(ctx context.Context, id *models.ID) (*models.Empty, error) {
+	_, err := endpoint.ProjectActionHandler.GetProject(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ObjectStorageHandler.DeleteObjectsWithPrefix(endpoint.ObjectGroupHandler.BucketName, databasehandler.ObjectKeyPrefix(id.GetID()))
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoint.ProjectActionHandler.DeleteProject(id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &models.Empty{}, nil
 }
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ScienceObjectsDB/ScienceObjectsDBServer/apierrors"
+	"github.com/ScienceObjectsDB/go-api/models"
+)
+
+//TokenEndpoints Handles the API token related gRPC endpoints
+type TokenEndpoints struct {
+	*GenericEndpoints
+}
+
+//NewTokenEndpoints New endpoints for API token handling
+func NewTokenEndpoints(genericEndpoints *GenericEndpoints) (*TokenEndpoints, error) {
+	return &TokenEndpoints{
+		GenericEndpoints: genericEndpoints,
+	}, nil
+}
+
+//CreateToken Creates a new API token for the requesting user
+//The user has to hold all requested rights on the project or dataset the token is scoped to
+//The secret of the token is only returned by this call
+func (endpoints *TokenEndpoints) CreateToken(ctx context.Context, request *models.CreateTokenRequest) (*models.TokenEntry, error) {
+	userID, err := endpoints.AuthHandler.UserID(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	// The resource type is part of the request, so the rights can not be checked by the authorization interceptor
+	for _, right := range request.GetRights() {
+		authorized, err := endpoints.AuthHandler.Authorize(ctx, request.GetResource(), right, request.GetResourceID())
+		if err != nil {
+			log.Println(err.Error())
+			return nil, err
+		}
+
+		if !authorized {
+			err := apierrors.NewPermissionDenied(right, request.GetResource(), request.GetResourceID())
+			log.Println(err.Error())
+			return nil, err
+		}
+	}
+
+	token, err := endpoints.TokenHandler.CreateToken(userID, request)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return token, nil
+}
+
+//GetUserTokens Lists all API tokens of the requesting user without their secrets
+func (endpoints *TokenEndpoints) GetUserTokens(ctx context.Context, _ *models.Empty) (*models.TokenList, error) {
+	userID, err := endpoints.AuthHandler.UserID(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	tokens, err := endpoints.TokenHandler.GetUserTokens(userID)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	tokenList := models.TokenList{
+		Token: tokens,
+	}
+
+	return &tokenList, nil
+}
+
+//RevokeToken Revokes an API token of the requesting user
+func (endpoints *TokenEndpoints) RevokeToken(ctx context.Context, id *models.ID) (*models.Empty, error) {
+	userID, err := endpoints.AuthHandler.UserID(ctx)
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	err = endpoints.TokenHandler.RevokeToken(userID, id.GetID())
+	if err != nil {
+		log.Println(err.Error())
+		return nil, err
+	}
+
+	return &models.Empty{}, nil
+}